@@ -0,0 +1,348 @@
+// Package client buffers keys of the Key Generation Service for services that hand them out one at a time.
+package client
+
+import (
+	"KeyGenerationService/internal/controller"
+	"KeyGenerationService/internal/handler/gRPC/gen"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LetterBytes contains all possible characters of a key generated by the Key Generation Service.
+const LetterBytes = controller.LetterBytes
+
+// AliasRules decides which aliases can be reserved with ReserveKey. They must match the rules the Key Generation
+// Service is configured with.
+type AliasRules = controller.AliasRules
+
+// DefaultAliasRules are the alias rules of the Key Generation Service when it isn't configured otherwise.
+var DefaultAliasRules = controller.DefaultAliasRules
+
+var (
+	ErrInvalidBatchSize  = errors.New("error cannot have batch size equal or smaller than 0")
+	ErrInvalidBufferSize = errors.New("error cannot have buffer size smaller than batch size")
+	ErrInvalidThreshold  = errors.New("error cannot have refill threshold smaller than 0, or equal or bigger than buffer size")
+	ErrInvalidBackoff    = errors.New("error cannot have backoff equal or smaller than 0, or max backoff smaller than backoff")
+	ErrInvalidTimeout    = errors.New("error cannot have call timeout equal or smaller than 0")
+	ErrFetchKeys         = errors.New("error fetching keys from the Key Generation Service")
+	ErrClosed            = errors.New("error buffer is closed")
+	ErrInvalidRules      = controller.ErrInvalidRules
+	ErrInvalidAlias      = errors.New("error alias is rejected by the Key Generation Service")
+	ErrAliasUsed         = errors.New("error alias is already leased or used")
+	ErrReserveKey        = errors.New("error reserving alias at the Key Generation Service")
+)
+
+// Buffer hands out keys of the Key Generation Service from a local ring buffer. Once fewer than threshold keys are
+// left, the next batch is fetched through GetKeyMetadata in the background, so Next rarely waits for a round trip.
+// Fetches failing with Unavailable or DeadlineExceeded are retried with exponential backoff.
+// Keys left in the buffer when it's closed are lost, the Key Generation Service already marked them used.
+type Buffer struct {
+	client gen.KeyGenerationServiceClient
+
+	batchSize   int
+	bufferSize  int
+	threshold   int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	callTimeout time.Duration
+
+	// ctx is cancelled by Close, aborting a running fetch.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards all fields below.
+	mu sync.Mutex
+	// ring holds count keys starting at head.
+	ring  []string
+	head  int
+	count int
+	// fetching is set while a fetch goroutine runs, there is at most one.
+	fetching bool
+	// err is the error of the latest failed GetKeyMetadata call, it's cleared once keys arrive or a new fetch starts.
+	err error
+	// retryAt holds off prefetching after a fetch gave up, only callers finding the buffer empty fetch before.
+	retryAt time.Time
+	// ready is closed and replaced whenever keys arrive, a fetch gives up or the buffer is closed.
+	ready  chan struct{}
+	closed bool
+}
+
+// Option configures optional behaviour of Buffer.
+type Option func(*Buffer)
+
+// WithBatchSize sets the most keys fetched per GetKeyMetadata call. Defaults to 100.
+func WithBatchSize(n int) Option {
+	return func(b *Buffer) {
+		b.batchSize = n
+	}
+}
+
+// WithBufferSize sets the most keys held in the buffer. Defaults to twice the batch size.
+func WithBufferSize(n int) Option {
+	return func(b *Buffer) {
+		b.bufferSize = n
+	}
+}
+
+// WithRefillThreshold starts a fetch once fewer than n keys are left. Zero only fetches once the buffer is empty.
+// Defaults to the buffer size minus the batch size, so a full batch always fits.
+func WithRefillThreshold(n int) Option {
+	return func(b *Buffer) {
+		b.threshold = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry of a failed fetch, doubling up to maxBackoff with each further
+// retry. A longer delay asked for by the Key Generation Service is respected. Defaults to 100ms and 10s.
+func WithBackoff(initial, maxBackoff time.Duration) Option {
+	return func(b *Buffer) {
+		b.minBackoff = initial
+		b.maxBackoff = maxBackoff
+	}
+}
+
+// WithCallTimeout sets the deadline of every GetKeyMetadata call. Defaults to 5s.
+func WithCallTimeout(d time.Duration) Option {
+	return func(b *Buffer) {
+		b.callTimeout = d
+	}
+}
+
+// New creates a Buffer fetching keys through cc, a connection to the Key Generation Service, and starts filling it.
+func New(cc grpc.ClientConnInterface, opts ...Option) (*Buffer, error) {
+	b := &Buffer{
+		client:      gen.NewKeyGenerationServiceClient(cc),
+		batchSize:   100,
+		threshold:   -1,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  10 * time.Second,
+		callTimeout: 5 * time.Second,
+		ready:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.batchSize <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+	if b.bufferSize == 0 {
+		b.bufferSize = 2 * b.batchSize
+	}
+	if b.bufferSize < b.batchSize {
+		return nil, ErrInvalidBufferSize
+	}
+	if b.threshold == -1 {
+		b.threshold = b.bufferSize - b.batchSize
+	}
+	if b.threshold < 0 || b.threshold >= b.bufferSize {
+		return nil, ErrInvalidThreshold
+	}
+	if b.minBackoff <= 0 || b.maxBackoff < b.minBackoff {
+		return nil, ErrInvalidBackoff
+	}
+	if b.callTimeout <= 0 {
+		return nil, ErrInvalidTimeout
+	}
+
+	b.ring = make([]string, b.bufferSize)
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+
+	b.mu.Lock()
+	b.prefetch()
+	b.mu.Unlock()
+	return b, nil
+}
+
+// Next returns an unused key, waiting for the running fetch if the buffer is empty. If that fetch gives up, its error
+// is returned, and the next call starts a new one.
+func (b *Buffer) Next(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for waited := false; ; waited = true {
+		if b.closed {
+			return "", ErrClosed
+		}
+		if b.count > 0 {
+			key := b.pop()
+			if time.Now().After(b.retryAt) {
+				b.prefetch()
+			}
+			return key, nil
+		}
+		if waited && !b.fetching && b.err != nil {
+			return "", b.err
+		}
+
+		b.prefetch()
+		ready := b.ready
+		b.mu.Unlock()
+		select {
+		case <-ready:
+			b.mu.Lock()
+		case <-ctx.Done():
+			b.mu.Lock()
+			if b.err != nil {
+				return "", fmt.Errorf("%w: %w", ctx.Err(), b.err)
+			}
+			return "", ctx.Err()
+		}
+	}
+}
+
+// ReserveKey reserves alias at the Key Generation Service, so it's never handed out as a key. An alias breaking the
+// alias rules or blocked by the Key Generation Service fails with ErrInvalidAlias, one already handed out with
+// ErrAliasUsed. Other errors are reported as ErrReserveKey, wrapping the gRPC status.
+func (b *Buffer) ReserveKey(ctx context.Context, alias string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.callTimeout)
+	defer cancel()
+
+	_, err := b.client.ReserveKey(ctx, &gen.ReserveKeyRequest{Alias: alias})
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %w", ErrInvalidAlias, err)
+	case codes.AlreadyExists:
+		return fmt.Errorf("%w: %w", ErrAliasUsed, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrReserveKey, err)
+	}
+}
+
+// Len returns the amount of keys left in the buffer.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Close stops fetching keys and makes Next fail with ErrClosed.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		b.cancel()
+		b.signal()
+	}
+	return nil
+}
+
+// prefetch starts a fetch if the buffer runs low and none is running. mu must be held.
+func (b *Buffer) prefetch() {
+	if b.closed || b.fetching || !b.low() {
+		return
+	}
+	b.fetching = true
+	b.err = nil
+	go b.fetch()
+}
+
+// low checks whether the buffer needs more keys. mu must be held.
+func (b *Buffer) low() bool {
+	return b.count == 0 || b.count < b.threshold
+}
+
+// fetch calls GetKeyMetadata until the buffer no longer runs low, retrying transient failures with backoff.
+func (b *Buffer) fetch() {
+	backoff := b.minBackoff
+	for {
+		b.mu.Lock()
+		if b.closed || !b.low() {
+			b.fetching = false
+			b.mu.Unlock()
+			return
+		}
+		n := min(b.batchSize, b.bufferSize-b.count)
+		b.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(b.ctx, b.callTimeout)
+		resp, err := b.client.GetKeyMetadata(ctx, &gen.GetKeyMetadataRequest{RequiredKeys: int64(n)})
+		cancel()
+		if err == nil && (!resp.Success || len(resp.Keys) == 0) {
+			err = errors.New("no keys returned")
+		}
+
+		b.mu.Lock()
+		if err == nil {
+			b.push(resp.Keys)
+			b.err = nil
+			b.signal()
+			b.mu.Unlock()
+			backoff = b.minBackoff
+			continue
+		}
+
+		b.err = fmt.Errorf("%w: %w", ErrFetchKeys, err)
+		if !retryable(err) || b.closed {
+			b.retryAt = time.Now().Add(retryDelay(err, b.minBackoff))
+			b.fetching = false
+			b.signal()
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(retryDelay(err, backoff)):
+		case <-b.ctx.Done():
+		}
+		backoff = min(2*backoff, b.maxBackoff)
+	}
+}
+
+// pop removes the oldest key from the ring. mu must be held and count bigger than 0.
+func (b *Buffer) pop() string {
+	key := b.ring[b.head]
+	b.ring[b.head] = ""
+	b.head = (b.head + 1) % len(b.ring)
+	b.count--
+	return key
+}
+
+// push appends keys to the ring. Keys not fitting into the ring are dropped, fetch never asks for more than fit.
+// mu must be held.
+func (b *Buffer) push(keys []string) {
+	for _, key := range keys {
+		if b.count == len(b.ring) {
+			return
+		}
+		b.ring[(b.head+b.count)%len(b.ring)] = key
+		b.count++
+	}
+}
+
+// signal wakes all callers of Next waiting for keys. mu must be held.
+func (b *Buffer) signal() {
+	close(b.ready)
+	b.ready = make(chan struct{})
+}
+
+// retryable checks whether a failed GetKeyMetadata call may succeed if retried.
+func retryable(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// retryDelay returns backoff with jitter, or the delay asked for by the Key Generation Service if it's longer.
+func retryDelay(err error, backoff time.Duration) time.Duration {
+	delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay.AsDuration() > delay {
+			delay = info.RetryDelay.AsDuration()
+		}
+	}
+	return delay
+}
@@ -0,0 +1,259 @@
+package client
+
+import (
+	"KeyGenerationService/internal/handler/gRPC/gen"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeKGS hands out the keys "key0", "key1", ... after failing its first unavailable calls with Unavailable, and
+// fails with ResourceExhausted once its pool of poolSize keys is drained.
+type fakeKGS struct {
+	gen.UnimplementedKeyGenerationServiceServer
+	poolSize    int64
+	unavailable int64
+	next        atomic.Int64
+	calls       atomic.Int64
+}
+
+func (f *fakeKGS) GetKeyMetadata(_ context.Context, req *gen.GetKeyMetadataRequest) (*gen.GetKeyMetadataResponse, error) {
+	if f.calls.Add(1) <= f.unavailable {
+		return &gen.GetKeyMetadataResponse{Success: false}, status.Error(codes.Unavailable, "database unavailable")
+	}
+	end := f.next.Add(req.RequiredKeys)
+	if end > f.poolSize {
+		return &gen.GetKeyMetadataResponse{Success: false}, status.Error(codes.ResourceExhausted, "key pool exhausted")
+	}
+
+	keys := make([]string, 0, req.RequiredKeys)
+	for i := end - req.RequiredKeys; i < end; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	return &gen.GetKeyMetadataResponse{Success: true, Keys: keys}, nil
+}
+
+// ReserveKey reserves every alias but "taken", which was handed out already, and "down", which fails with
+// Unavailable. Aliases breaking the default alias rules are rejected.
+func (f *fakeKGS) ReserveKey(_ context.Context, req *gen.ReserveKeyRequest) (*gen.ReserveKeyResponse, error) {
+	switch {
+	case !DefaultAliasRules.Valid(req.Alias):
+		return &gen.ReserveKeyResponse{Success: false}, status.Error(codes.InvalidArgument, "invalid alias")
+	case req.Alias == "taken":
+		return &gen.ReserveKeyResponse{Success: false}, status.Error(codes.AlreadyExists, "alias used")
+	case req.Alias == "down":
+		return &gen.ReserveKeyResponse{Success: false}, status.Error(codes.Unavailable, "database unavailable")
+	}
+	return &gen.ReserveKeyResponse{Success: true, Key: req.Alias}, nil
+}
+
+// newTestConn serves kgs over an in-process connection.
+func newTestConn(t *testing.T, kgs gen.KeyGenerationServiceServer) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	gen.RegisterKeyGenerationServiceServer(srv, kgs)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing Key Generation Service: %v.\n", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+// newTestBuffer creates a Buffer fetching from kgs, closed when the test ends.
+func newTestBuffer(t *testing.T, kgs gen.KeyGenerationServiceServer, opts ...Option) *Buffer {
+	t.Helper()
+
+	opts = append([]Option{WithBackoff(time.Millisecond, 10*time.Millisecond)}, opts...)
+	b, err := New(newTestConn(t, kgs), opts...)
+	if err != nil {
+		t.Fatalf("Error creating buffer: %v.\n", err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+	return b
+}
+
+// waitForLen waits until b holds want keys.
+func waitForLen(t *testing.T, b *Buffer, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Len() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Error incorrect amount of buffered keys: Have %v, want %v.\n", b.Len(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		opts []Option
+		want error
+	}{
+		{[]Option{}, nil},
+		{[]Option{WithBatchSize(0)}, ErrInvalidBatchSize},
+		{[]Option{WithBatchSize(10), WithBufferSize(5)}, ErrInvalidBufferSize},
+		{[]Option{WithRefillThreshold(-2)}, ErrInvalidThreshold},
+		{[]Option{WithBatchSize(10), WithRefillThreshold(20)}, ErrInvalidThreshold},
+		{[]Option{WithBackoff(0, time.Second)}, ErrInvalidBackoff},
+		{[]Option{WithBackoff(time.Second, time.Millisecond)}, ErrInvalidBackoff},
+		{[]Option{WithCallTimeout(0)}, ErrInvalidTimeout},
+		{[]Option{WithBatchSize(10), WithBufferSize(15), WithRefillThreshold(0)}, nil},
+	}
+
+	conn := newTestConn(t, &fakeKGS{poolSize: 1000})
+	for _, c := range cases {
+		b, err := New(conn, c.opts...)
+		if !errors.Is(err, c.want) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, c.want)
+		}
+		if b != nil {
+			_ = b.Close()
+		}
+	}
+}
+
+func TestBuffer_Next(t *testing.T) {
+	kgs := &fakeKGS{poolSize: 1000}
+	b := newTestBuffer(t, kgs, WithBatchSize(10))
+	ctx := context.Background()
+
+	// 1. The buffer is filled up to the threshold right away.
+	waitForLen(t, b, 10)
+
+	// 2. Keys are never handed out twice, even to concurrent callers.
+	var mu sync.Mutex
+	seen := make(map[string]struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := b.Next(ctx)
+			if err != nil {
+				t.Errorf("Error getting key: %v.\n", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if _, ok := seen[key]; ok {
+				t.Errorf("Error key %v is handed out more than once.\n", key)
+			}
+			seen[key] = struct{}{}
+		}()
+	}
+	wg.Wait()
+
+	// 3. Once under the threshold, the buffer is refilled in the background, without a caller waiting.
+	waitForLen(t, b, 10)
+	for i := 0; i < 5; i++ {
+		_, _ = b.Next(ctx)
+	}
+	waitForLen(t, b, 15)
+
+	// 4. Keys are handed out in the order they were fetched.
+	var first, second int
+	key, _ := b.Next(ctx)
+	_, _ = fmt.Sscanf(key, "key%d", &first)
+	key, _ = b.Next(ctx)
+	_, _ = fmt.Sscanf(key, "key%d", &second)
+	if first >= second {
+		t.Errorf("Error keys out of order: Have key%v before key%v.\n", first, second)
+	}
+}
+
+func TestBuffer_Retry(t *testing.T) {
+	kgs := &fakeKGS{poolSize: 1000, unavailable: 3}
+	b := newTestBuffer(t, kgs, WithBatchSize(10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := b.Next(ctx); err != nil {
+		t.Fatalf("Error getting key: %v.\n", err)
+	}
+	if calls := kgs.calls.Load(); calls < 4 {
+		t.Errorf("Error incorrect amount of calls: Have %v, want at least %v.\n", calls, 4)
+	}
+}
+
+func TestBuffer_Errors(t *testing.T) {
+	// 1. Errors other than Unavailable aren't retried, and are returned once the buffer is empty.
+	kgs := &fakeKGS{poolSize: 15}
+	b := newTestBuffer(t, kgs, WithBatchSize(10), WithRefillThreshold(0))
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if _, err := b.Next(ctx); err != nil {
+			t.Fatalf("Error getting key: %v.\n", err)
+		}
+	}
+	_, err := b.Next(ctx)
+	if !errors.Is(err, ErrFetchKeys) || status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrFetchKeys)
+	}
+
+	// 2. Callers stop waiting for a fetch that keeps being retried once their context is done.
+	kgs = &fakeKGS{poolSize: 1000, unavailable: 1 << 30}
+	b = newTestBuffer(t, kgs)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = b.Next(timeoutCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, context.DeadlineExceeded)
+	}
+
+	// 3. A closed buffer hands out no more keys.
+	_ = b.Close()
+	_, err = b.Next(ctx)
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrClosed)
+	}
+}
+
+func TestBuffer_ReserveKey(t *testing.T) {
+	b := newTestBuffer(t, &fakeKGS{poolSize: 1000})
+
+	cases := []struct {
+		alias string
+		want  error
+	}{
+		{"launch", nil},
+		{"my-promo", nil},
+		{"a/b", ErrInvalidAlias},
+		{"taken", ErrAliasUsed},
+		{"down", ErrReserveKey},
+	}
+	for _, c := range cases {
+		if err := b.ReserveKey(context.Background(), c.alias); !errors.Is(err, c.want) {
+			t.Errorf("Error incorrect error reserving %v: Have %v, want %v.\n", c.alias, err, c.want)
+		}
+	}
+}
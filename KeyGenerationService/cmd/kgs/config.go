@@ -0,0 +1,264 @@
+package main
+
+import (
+	"KeyGenerationService/internal/auth"
+	"KeyGenerationService/internal/controller"
+	"KeyGenerationService/internal/repository/psql"
+	"KeyGenerationService/internal/repository/redis"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	backendMemory     = "memory"
+	backendPSQL       = "psql"
+	backendPSQLSingle = "psql-single"
+	backendBolt       = "bolt"
+	backendRedis      = "redis"
+
+	keySourceFast        = "fast"
+	keySourceSecure      = "secure"
+	keySourcePermutation = "permutation"
+
+	policyDrainOldFirst     = "drain-old-first"
+	policyCurrentLengthOnly = "current-length-only"
+)
+
+var (
+	ErrUnknownBackend   = errors.New("error unknown database backend")
+	ErrUnknownKeySource = errors.New("error unknown key source")
+	ErrInvalidEnv       = errors.New("error invalid environment variable")
+	ErrMissingSecret    = errors.New("error permutation key source requires a secret")
+	ErrUnknownPolicy    = errors.New("error unknown key length policy")
+	ErrMissingTLSCert   = errors.New("error TLS client authentication requires a server certificate and key")
+	ErrInvalidLimit     = errors.New("error cannot have a negative batch size or quota, or a quota period equal or smaller than 0")
+)
+
+// config holds everything needed to start the Key Generation Service.
+// Each field can be set by a flag or an environment variable, flags take precedence.
+type config struct {
+	addr            string
+	shutdownTimeout time.Duration
+
+	tls        auth.TLSConfig
+	tokensFile string
+	auditLog   bool
+
+	backend  string
+	psql     psql.Config
+	migrate  bool
+	boltPath string
+	redis    redis.Config
+
+	poolSize          int
+	keyLength         int
+	keySource         string
+	permutationSecret string
+	keyLengthPolicy   string
+	growthThreshold   float64
+	maxKeyLength      int
+	lowWaterMark      int
+	highWaterMark     int
+	replenishInterval time.Duration
+	leaseReapInterval time.Duration
+	aliasRules        controller.AliasRules
+	blocklistFile     string
+	reservedPaths     []string
+
+	maxBatchSize      int
+	clientQuota       int
+	clientQuotaPeriod time.Duration
+
+	// args are the positional arguments left after the flags.
+	args []string
+}
+
+// loadConfig parses args into a config, falling back to environment variables read by getenv and then to defaults.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	var cfg config
+	env := envReader{getenv: getenv}
+
+	fs := flag.NewFlagSet("kgs", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", env.string("KGS_ADDR", ":50051"), "gRPC listen address")
+	fs.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", env.duration("KGS_SHUTDOWN_TIMEOUT", 10*time.Second), "time to drain in-flight RPCs before forcing shutdown")
+	fs.StringVar(&cfg.tls.CertFile, "tls-cert", env.string("KGS_TLS_CERT", ""), "server certificate, serves plaintext gRPC if unset")
+	fs.StringVar(&cfg.tls.KeyFile, "tls-key", env.string("KGS_TLS_KEY", ""), "key of the server certificate")
+	fs.StringVar(&cfg.tls.ClientCAFile, "tls-client-ca", env.string("KGS_TLS_CLIENT_CA", ""), "CA that signs client certificates, turns on mutual TLS")
+	allowedClients := fs.String("tls-allowed-clients", env.string("KGS_TLS_ALLOWED_CLIENTS", ""), "comma-separated common names or DNS names of the client certificates allowed to connect")
+	fs.StringVar(&cfg.tokensFile, "auth-tokens-file", env.string("KGS_AUTH_TOKENS_FILE", ""), "file of '<identity> <token>' lines, every call must carry one of the tokens if set")
+	fs.BoolVar(&cfg.auditLog, "audit-log", env.bool("KGS_AUDIT_LOG", false), "log which client took, confirmed or released which keys")
+
+	fs.StringVar(&cfg.backend, "backend", env.string("KGS_BACKEND", backendMemory), "database backend: memory, psql, psql-single, bolt or redis")
+	fs.StringVar(&cfg.psql.DSN, "psql-dsn", env.string("KGS_PSQL_DSN", ""), "PostgreSQL connection string or postgres:// URL, the other psql flags override it")
+	fs.StringVar(&cfg.psql.Host, "psql-host", env.string("KGS_PSQL_HOST", ""), "PostgreSQL host")
+	fs.IntVar(&cfg.psql.Port, "psql-port", env.int("KGS_PSQL_PORT", 0), "PostgreSQL port")
+	fs.StringVar(&cfg.psql.User, "psql-user", env.string("KGS_PSQL_USER", ""), "PostgreSQL user")
+	fs.StringVar(&cfg.psql.Password, "psql-password", env.string("KGS_PSQL_PASSWORD", ""), "PostgreSQL password")
+	fs.StringVar(&cfg.psql.Database, "psql-database", env.string("KGS_PSQL_DATABASE", ""), "PostgreSQL database name (default KeyGenerationService without a DSN)")
+	fs.StringVar(&cfg.psql.SSLMode, "psql-sslmode", env.string("KGS_PSQL_SSLMODE", ""), "PostgreSQL sslmode (default disable without a DSN)")
+	fs.StringVar(&cfg.psql.SSLRootCert, "psql-sslrootcert", env.string("KGS_PSQL_SSLROOTCERT", ""), "CA certificate verifying the PostgreSQL server")
+	fs.StringVar(&cfg.psql.SSLCert, "psql-sslcert", env.string("KGS_PSQL_SSLCERT", ""), "client certificate presented to PostgreSQL")
+	fs.StringVar(&cfg.psql.SSLKey, "psql-sslkey", env.string("KGS_PSQL_SSLKEY", ""), "key of the client certificate")
+	fs.IntVar(&cfg.psql.MaxOpenConns, "psql-max-open-conns", env.int("KGS_PSQL_MAX_OPEN_CONNS", 0), "maximum open PostgreSQL connections, 0 is unlimited")
+	fs.IntVar(&cfg.psql.MaxIdleConns, "psql-max-idle-conns", env.int("KGS_PSQL_MAX_IDLE_CONNS", 0), "maximum idle PostgreSQL connections, 0 keeps the default")
+	fs.DurationVar(&cfg.psql.ConnMaxLifetime, "psql-conn-max-lifetime", env.duration("KGS_PSQL_CONN_MAX_LIFETIME", 0), "maximum lifetime of a PostgreSQL connection, 0 is unlimited")
+	fs.DurationVar(&cfg.psql.StatementTimeout, "psql-statement-timeout", env.duration("KGS_PSQL_STATEMENT_TIMEOUT", 0), "abort PostgreSQL statements running longer than this, 0 disables it")
+	fs.StringVar(&cfg.boltPath, "bolt-path", env.string("KGS_BOLT_PATH", "kgs.db"), "database file of the bolt backend")
+	fs.StringVar(&cfg.redis.Addr, "redis-addr", env.string("KGS_REDIS_ADDR", "localhost:6379"), "Redis address of the redis backend")
+	fs.StringVar(&cfg.redis.Username, "redis-username", env.string("KGS_REDIS_USERNAME", ""), "Redis ACL user")
+	fs.StringVar(&cfg.redis.Password, "redis-password", env.string("KGS_REDIS_PASSWORD", ""), "Redis password")
+	fs.IntVar(&cfg.redis.DB, "redis-db", env.int("KGS_REDIS_DB", 0), "Redis database number")
+	fs.StringVar(&cfg.redis.Prefix, "redis-prefix", env.string("KGS_REDIS_PREFIX", "kgs:"), "prefix of every Redis key of the key pool")
+	fs.BoolVar(&cfg.migrate, "migrate", env.bool("KGS_MIGRATE", false), "apply pending schema migrations on startup (psql backends only)")
+
+	fs.IntVar(&cfg.poolSize, "pool-size", env.int("KGS_POOL_SIZE", 10000), "amount of keys generated at startup")
+	fs.IntVar(&cfg.keyLength, "key-length", env.int("KGS_KEY_LENGTH", 4), "length of generated keys")
+	fs.StringVar(&cfg.keySource, "key-source", env.string("KGS_KEY_SOURCE", keySourceSecure), "key generator: fast (math/rand), secure (crypto/rand) or permutation (keyed enumeration of the key space)")
+	fs.StringVar(&cfg.permutationSecret, "permutation-secret", env.string("KGS_PERMUTATION_SECRET", ""), "secret of the permutation key source, must never change for a key pool")
+	fs.StringVar(&cfg.keyLengthPolicy, "key-length-policy", env.string("KGS_KEY_LENGTH_POLICY", policyDrainOldFirst), "keys handed out after a key length change: drain-old-first or current-length-only")
+	fs.Float64Var(&cfg.growthThreshold, "key-length-growth-threshold", env.float("KGS_KEY_LENGTH_GROWTH_THRESHOLD", 0), "switch to the next key length when less than this fraction of the key space is left, 0 disables it")
+	fs.IntVar(&cfg.maxKeyLength, "max-key-length", env.int("KGS_MAX_KEY_LENGTH", 8), "longest key length the key length can grow to")
+	fs.IntVar(&cfg.lowWaterMark, "low-water-mark", env.int("KGS_LOW_WATER_MARK", 2000), "refill the pool when unused keys fall below this amount")
+	fs.IntVar(&cfg.highWaterMark, "high-water-mark", env.int("KGS_HIGH_WATER_MARK", 10000), "amount of unused keys the pool is refilled to")
+	fs.DurationVar(&cfg.replenishInterval, "replenish-interval", env.duration("KGS_REPLENISH_INTERVAL", 5*time.Second), "how often the pool size is checked")
+	fs.DurationVar(&cfg.leaseReapInterval, "lease-reap-interval", env.duration("KGS_LEASE_REAP_INTERVAL", 30*time.Second), "how often keys of expired leases are returned to the pool")
+
+	fs.IntVar(&cfg.aliasRules.MinLength, "alias-min-length", env.int("KGS_ALIAS_MIN_LENGTH", controller.DefaultAliasRules.MinLength), "shortest alias clients can reserve")
+	fs.IntVar(&cfg.aliasRules.MaxLength, "alias-max-length", env.int("KGS_ALIAS_MAX_LENGTH", controller.DefaultAliasRules.MaxLength), "longest alias clients can reserve")
+	fs.StringVar(&cfg.aliasRules.Alphabet, "alias-alphabet", env.string("KGS_ALIAS_ALPHABET", controller.DefaultAliasRules.Alphabet), "characters an alias can contain")
+	fs.StringVar(&cfg.blocklistFile, "blocklist-file", env.string("KGS_BLOCKLIST_FILE", ""), "file of words, one per line, that keys and aliases must not contain, also when spelled in leetspeak")
+	reservedPaths := fs.String("reserved-paths", env.string("KGS_RESERVED_PATHS", strings.Join(controller.DefaultReservedPaths, ",")), "comma-separated paths that keys and aliases must not be equal to")
+
+	fs.IntVar(&cfg.maxBatchSize, "max-batch-size", env.int("KGS_MAX_BATCH_SIZE", 1000), "most keys a single request can ask for, 0 is unbounded")
+	fs.IntVar(&cfg.clientQuota, "client-quota", env.int("KGS_CLIENT_QUOTA", 0), "keys every client can take per client quota period, 0 disables quotas")
+	fs.DurationVar(&cfg.clientQuotaPeriod, "client-quota-period", env.duration("KGS_CLIENT_QUOTA_PERIOD", time.Minute), "period of the client quota")
+
+	if env.err != nil {
+		return config{}, env.err
+	}
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	cfg.args = fs.Args()
+	for _, client := range strings.Split(*allowedClients, ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			cfg.tls.AllowedClients = append(cfg.tls.AllowedClients, client)
+		}
+	}
+	for _, path := range strings.Split(*reservedPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			cfg.reservedPaths = append(cfg.reservedPaths, path)
+		}
+	}
+
+	// Without a DSN, keep connecting to the local database the service always used.
+	if cfg.psql.DSN == "" {
+		if cfg.psql.Database == "" {
+			cfg.psql.Database = "KeyGenerationService"
+		}
+		if cfg.psql.SSLMode == "" {
+			cfg.psql.SSLMode = "disable"
+		}
+	}
+
+	switch cfg.backend {
+	case backendMemory, backendPSQL, backendPSQLSingle, backendBolt, backendRedis:
+	default:
+		return config{}, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.backend)
+	}
+	switch cfg.keySource {
+	case keySourceFast, keySourceSecure:
+	case keySourcePermutation:
+		if cfg.permutationSecret == "" {
+			return config{}, ErrMissingSecret
+		}
+	default:
+		return config{}, fmt.Errorf("%w: %q", ErrUnknownKeySource, cfg.keySource)
+	}
+	if cfg.keyLengthPolicy != policyDrainOldFirst && cfg.keyLengthPolicy != policyCurrentLengthOnly {
+		return config{}, fmt.Errorf("%w: %q", ErrUnknownPolicy, cfg.keyLengthPolicy)
+	}
+	if (cfg.tls.CertFile == "") != (cfg.tls.KeyFile == "") || (cfg.tls.CertFile == "" && cfg.tls.ClientCAFile != "") {
+		return config{}, ErrMissingTLSCert
+	}
+	if cfg.maxBatchSize < 0 || cfg.clientQuota < 0 || cfg.clientQuotaPeriod <= 0 {
+		return config{}, ErrInvalidLimit
+	}
+
+	return cfg, nil
+}
+
+// envReader reads typed environment variables, keeping the first parsing error.
+type envReader struct {
+	getenv func(string) string
+	err    error
+}
+
+func (e *envReader) string(name, fallback string) string {
+	if v := e.getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (e *envReader) int(name string, fallback int) int {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return n
+}
+
+func (e *envReader) bool(name string, fallback bool) bool {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return b
+}
+
+func (e *envReader) float(name string, fallback float64) float64 {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return f
+}
+
+func (e *envReader) duration(name string, fallback time.Duration) time.Duration {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return d
+}
+
+func (e *envReader) setErr(name, value string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%w: %s=%q", ErrInvalidEnv, name, value)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"KeyGenerationService/internal/controller"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Test defaults", func(t *testing.T) {
+		cfg, err := loadConfig(nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.backend != backendMemory {
+			t.Errorf("Error incorrect backend: Have %v, want %v.\n", cfg.backend, backendMemory)
+		}
+		if cfg.keySource != keySourceSecure {
+			t.Errorf("Error incorrect key source: Have %v, want %v.\n", cfg.keySource, keySourceSecure)
+		}
+		if cfg.keyLength != 4 {
+			t.Errorf("Error incorrect key length: Have %v, want %v.\n", cfg.keyLength, 4)
+		}
+		if cfg.aliasRules != controller.DefaultAliasRules {
+			t.Errorf("Error incorrect alias rules: Have %v, want %v.\n", cfg.aliasRules, controller.DefaultAliasRules)
+		}
+	})
+
+	t.Run("Test alias rules", func(t *testing.T) {
+		env := map[string]string{"KGS_ALIAS_ALPHABET": "abc"}
+		args := []string{"-alias-min-length", "5", "-alias-max-length", "10"}
+		cfg, err := loadConfig(args, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		want := controller.AliasRules{MinLength: 5, MaxLength: 10, Alphabet: "abc"}
+		if cfg.aliasRules != want {
+			t.Errorf("Error incorrect alias rules: Have %v, want %v.\n", cfg.aliasRules, want)
+		}
+	})
+
+	t.Run("Test blocklist", func(t *testing.T) {
+		cfg, err := loadConfig(nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if !reflect.DeepEqual(cfg.reservedPaths, controller.DefaultReservedPaths) {
+			t.Errorf("Error incorrect reserved paths: Have %v, want %v.\n", cfg.reservedPaths, controller.DefaultReservedPaths)
+		}
+
+		env := map[string]string{"KGS_BLOCKLIST_FILE": "words.txt"}
+		cfg, err = loadConfig([]string{"-reserved-paths", "api, ,docs"}, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if want := []string{"api", "docs"}; !reflect.DeepEqual(cfg.reservedPaths, want) {
+			t.Errorf("Error incorrect reserved paths: Have %v, want %v.\n", cfg.reservedPaths, want)
+		}
+		if cfg.blocklistFile != "words.txt" {
+			t.Errorf("Error incorrect blocklist file: Have %v, want %v.\n", cfg.blocklistFile, "words.txt")
+		}
+	})
+
+	t.Run("Test environment variables", func(t *testing.T) {
+		env := map[string]string{
+			"KGS_BACKEND":            backendPSQL,
+			"KGS_POOL_SIZE":          "500",
+			"KGS_REPLENISH_INTERVAL": "1m",
+		}
+		cfg, err := loadConfig(nil, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.backend != backendPSQL {
+			t.Errorf("Error incorrect backend: Have %v, want %v.\n", cfg.backend, backendPSQL)
+		}
+		if cfg.poolSize != 500 {
+			t.Errorf("Error incorrect pool size: Have %v, want %v.\n", cfg.poolSize, 500)
+		}
+		if cfg.replenishInterval != time.Minute {
+			t.Errorf("Error incorrect replenish interval: Have %v, want %v.\n", cfg.replenishInterval, time.Minute)
+		}
+	})
+
+	t.Run("Test flags take precedence over environment variables", func(t *testing.T) {
+		env := map[string]string{"KGS_POOL_SIZE": "500"}
+		cfg, err := loadConfig([]string{"-pool-size", "20"}, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.poolSize != 20 {
+			t.Errorf("Error incorrect pool size: Have %v, want %v.\n", cfg.poolSize, 20)
+		}
+	})
+
+	t.Run("Test PostgreSQL connection", func(t *testing.T) {
+		cfg, err := loadConfig(nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.psql.Database != "KeyGenerationService" || cfg.psql.SSLMode != "disable" {
+			t.Errorf("Error incorrect local defaults: Have %v and %v, want %v and %v.\n", cfg.psql.Database, cfg.psql.SSLMode, "KeyGenerationService", "disable")
+		}
+
+		env := map[string]string{
+			"KGS_PSQL_DSN":               "postgres://kgs@db.internal/kgs?sslmode=verify-full",
+			"KGS_PSQL_MAX_OPEN_CONNS":    "20",
+			"KGS_PSQL_STATEMENT_TIMEOUT": "3s",
+		}
+		cfg, err = loadConfig([]string{"-psql-sslrootcert", "/etc/ssl/root.crt"}, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.psql.Database != "" || cfg.psql.SSLMode != "" {
+			t.Errorf("Error local defaults shouldn't override the DSN: Have %v and %v.\n", cfg.psql.Database, cfg.psql.SSLMode)
+		}
+		if cfg.psql.MaxOpenConns != 20 || cfg.psql.StatementTimeout != 3*time.Second || cfg.psql.SSLRootCert != "/etc/ssl/root.crt" {
+			t.Errorf("Error incorrect PostgreSQL config: %+v.\n", cfg.psql)
+		}
+	})
+
+	t.Run("Test TLS allow-list", func(t *testing.T) {
+		args := []string{"-tls-cert", "kgs.crt", "-tls-key", "kgs.key", "-tls-client-ca", "ca.crt", "-tls-allowed-clients", "url-shortener, batch-job"}
+		cfg, err := loadConfig(args, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if len(cfg.tls.AllowedClients) != 2 || cfg.tls.AllowedClients[0] != "url-shortener" || cfg.tls.AllowedClients[1] != "batch-job" {
+			t.Errorf("Error incorrect allowed clients: %v.\n", cfg.tls.AllowedClients)
+		}
+	})
+
+	t.Run("Test invalid values", func(t *testing.T) {
+		_, err := loadConfig([]string{"-backend", "mysql"}, func(string) string { return "" })
+		if !errors.Is(err, ErrUnknownBackend) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrUnknownBackend)
+		}
+
+		_, err = loadConfig([]string{"-key-source", "dice"}, func(string) string { return "" })
+		if !errors.Is(err, ErrUnknownKeySource) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrUnknownKeySource)
+		}
+
+		_, err = loadConfig([]string{"-key-source", "permutation"}, func(string) string { return "" })
+		if !errors.Is(err, ErrMissingSecret) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrMissingSecret)
+		}
+
+		_, err = loadConfig([]string{"-key-length-policy", "newest"}, func(string) string { return "" })
+		if !errors.Is(err, ErrUnknownPolicy) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrUnknownPolicy)
+		}
+
+		_, err = loadConfig([]string{"-client-quota", "-1"}, func(string) string { return "" })
+		if !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidLimit)
+		}
+
+		_, err = loadConfig([]string{"-tls-client-ca", "ca.crt"}, func(string) string { return "" })
+		if !errors.Is(err, ErrMissingTLSCert) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrMissingTLSCert)
+		}
+
+		env := map[string]string{"KGS_KEY_LENGTH": "four"}
+		_, err = loadConfig(nil, func(name string) string { return env[name] })
+		if !errors.Is(err, ErrInvalidEnv) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidEnv)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"KeyGenerationService/internal/auth"
+	"KeyGenerationService/internal/controller"
+	"KeyGenerationService/internal/handler/gRPC"
+	"KeyGenerationService/internal/handler/gRPC/gen"
+	"KeyGenerationService/internal/repository"
+	"KeyGenerationService/internal/repository/bolt"
+	"KeyGenerationService/internal/repository/memory"
+	"KeyGenerationService/internal/repository/psql"
+	"KeyGenerationService/internal/repository/redis"
+	"context"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "migrate" {
+		cfg, err := loadConfig(args[1:], os.Getenv)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runMigrate(cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(args, os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the Key Generation Service and blocks until SIGINT or SIGTERM is received.
+func run(cfg config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := newDatabase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if c, ok := db.(io.Closer); ok {
+		defer func() { _ = c.Close() }()
+	}
+
+	opts, err := controllerOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	kgs, err := controller.New(ctx, db, cfg.poolSize, cfg.keyLength, opts...)
+	if err != nil {
+		return err
+	}
+
+	serverOpts, err := serverOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", cfg.addr)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer(serverOpts...)
+	gen.RegisterKeyGenerationServiceServer(srv, gRPC.New(kgs, handlerOptions(cfg)...))
+
+	// Background workers only return early on invalid configuration.
+	workers := 2
+	workersDone := make(chan error, workers)
+	go func() {
+		workersDone <- kgs.Replenish(ctx, cfg.lowWaterMark, cfg.highWaterMark, cfg.replenishInterval)
+	}()
+	go func() {
+		workersDone <- kgs.ReapLeases(ctx, cfg.leaseReapInterval)
+	}()
+
+	serveDone := make(chan error, 1)
+	go func() {
+		log.Printf("Key Generation Service listening on %s (backend: %s).\n", lis.Addr(), cfg.backend)
+		serveDone <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-serveDone:
+		return err
+	case err := <-workersDone:
+		srv.Stop()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, draining in-flight RPCs.")
+	shutdown(srv, cfg.shutdownTimeout)
+	for i := 0; i < workers; i++ {
+		<-workersDone
+	}
+
+	// Keys of reserved indexes that weren't written yet are lost once the service stops.
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err := kgs.Flush(flushCtx); err != nil {
+		log.Println(err)
+	}
+
+	return nil
+}
+
+// newDatabase creates the repository.KGSDatabase selected by cfg.backend.
+// With cfg.migrate set, pending schema migrations are applied before the psql backend is used.
+func newDatabase(ctx context.Context, cfg config) (repository.KGSDatabase, error) {
+	switch cfg.backend {
+	case backendPSQL, backendPSQLSingle:
+		db, err := psql.Open(ctx, cfg.psql)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.migrate {
+			version, err := db.MigrateUp(ctx, 0)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Database schema at version %d.\n", version)
+		}
+		if cfg.backend == backendPSQLSingle {
+			return db.SingleTable(), nil
+		}
+		return db, nil
+	case backendBolt:
+		return bolt.Open(cfg.boltPath)
+	case backendRedis:
+		return redis.Open(ctx, cfg.redis)
+	default:
+		return memory.New()
+	}
+}
+
+// controllerOptions translates cfg into the options of controller.New.
+func controllerOptions(cfg config) ([]controller.Option, error) {
+	var opts []controller.Option
+
+	switch cfg.keySource {
+	case keySourceFast:
+		opts = append(opts, controller.WithKeySource(controller.FastKeySource{}))
+	case keySourcePermutation:
+		p, err := controller.NewPermutation([]byte(cfg.permutationSecret))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, controller.WithPermutation(p))
+	default:
+		opts = append(opts, controller.WithKeySource(controller.SecureKeySource{}))
+	}
+
+	if cfg.keyLengthPolicy == policyCurrentLengthOnly {
+		opts = append(opts, controller.WithKeyLengthPolicy(controller.CurrentLengthOnly))
+	}
+	if cfg.growthThreshold > 0 {
+		opts = append(opts, controller.WithKeyLengthGrowth(cfg.growthThreshold, cfg.maxKeyLength))
+	}
+	if cfg.auditLog {
+		opts = append(opts, controller.WithAuditLog(log.New(os.Stderr, "audit: ", log.LstdFlags)))
+	}
+	opts = append(opts, controller.WithAliasRules(cfg.aliasRules))
+
+	var words []string
+	if cfg.blocklistFile != "" {
+		var err error
+		if words, err = controller.LoadWordList(cfg.blocklistFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(words) > 0 || len(cfg.reservedPaths) > 0 {
+		opts = append(opts, controller.WithKeyFilter(controller.NewBlocklist(words, cfg.reservedPaths)))
+	}
+
+	return opts, nil
+}
+
+// serverOptions translates cfg into the transport credentials and authentication interceptors of the gRPC server.
+func serverOptions(cfg config) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if cfg.tls.CertFile != "" {
+		creds, err := auth.ServerCredentials(cfg.tls)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	var tokens map[string]string
+	if cfg.tokensFile != "" {
+		var err error
+		if tokens, err = auth.LoadTokens(cfg.tokensFile); err != nil {
+			return nil, err
+		}
+	}
+	authenticator := auth.NewAuthenticator(tokens)
+	opts = append(opts,
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	)
+	return opts, nil
+}
+
+// handlerOptions translates cfg into the options of gRPC.New.
+func handlerOptions(cfg config) []gRPC.Option {
+	var opts []gRPC.Option
+	if cfg.maxBatchSize > 0 {
+		opts = append(opts, gRPC.WithMaxBatchSize(cfg.maxBatchSize))
+	}
+	if cfg.clientQuota > 0 {
+		opts = append(opts, gRPC.WithClientQuota(cfg.clientQuota, cfg.clientQuotaPeriod))
+	}
+	return opts
+}
+
+// shutdown stops srv gracefully, forcing it to stop if in-flight RPCs aren't done within timeout.
+func shutdown(srv *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		log.Println("Shutdown timeout exceeded, forcing stop.")
+		srv.Stop()
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"KeyGenerationService/internal/repository/psql"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+var (
+	ErrMigrateBackend = errors.New("error migrations require the psql backend")
+	ErrMigrateUsage   = errors.New("error usage: kgs migrate [flags] up [steps] | down [steps] | version")
+)
+
+// runMigrate runs the migrate subcommand in cfg.args against the psql backend.
+func runMigrate(cfg config) error {
+	if cfg.backend != backendPSQL && cfg.backend != backendPSQLSingle {
+		return ErrMigrateBackend
+	}
+
+	command, steps, err := parseMigrateArgs(cfg.args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := psql.Open(ctx, cfg.psql)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	switch command {
+	case "up":
+		version, err = db.MigrateUp(ctx, steps)
+	case "down":
+		version, err = db.MigrateDown(ctx, steps)
+	default:
+		version, err = db.MigrationVersion(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Database schema at version %d.\n", version)
+	return nil
+}
+
+// parseMigrateArgs parses the migrate subcommand and its amount of steps.
+// 'up' defaults to every pending migration, 'down' to a single step.
+func parseMigrateArgs(args []string) (string, int, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", 0, ErrMigrateUsage
+	}
+
+	command, steps := args[0], 0
+	switch command {
+	case "up":
+	case "down":
+		steps = 1
+	case "version":
+		if len(args) > 1 {
+			return "", 0, ErrMigrateUsage
+		}
+	default:
+		return "", 0, fmt.Errorf("%w: unknown command %q", ErrMigrateUsage, command)
+	}
+
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("%w: invalid steps %q", ErrMigrateUsage, args[1])
+		}
+		steps = n
+	}
+	return command, steps, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		wantCommand string
+		wantSteps   int
+		wantErr     error
+	}{
+		{name: "Test up", args: []string{"up"}, wantCommand: "up", wantSteps: 0},
+		{name: "Test up with steps", args: []string{"up", "2"}, wantCommand: "up", wantSteps: 2},
+		{name: "Test down defaults to a single step", args: []string{"down"}, wantCommand: "down", wantSteps: 1},
+		{name: "Test down with steps", args: []string{"down", "3"}, wantCommand: "down", wantSteps: 3},
+		{name: "Test version", args: []string{"version"}, wantCommand: "version", wantSteps: 0},
+		{name: "Test missing command", args: nil, wantErr: ErrMigrateUsage},
+		{name: "Test unknown command", args: []string{"sideways"}, wantErr: ErrMigrateUsage},
+		{name: "Test invalid steps", args: []string{"up", "zero"}, wantErr: ErrMigrateUsage},
+		{name: "Test non-positive steps", args: []string{"down", "0"}, wantErr: ErrMigrateUsage},
+		{name: "Test version with steps", args: []string{"version", "1"}, wantErr: ErrMigrateUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command, steps, err := parseMigrateArgs(tc.args)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Error incorrect error: Have %v, want %v.\n", err, tc.wantErr)
+			}
+			if command != tc.wantCommand {
+				t.Errorf("Error incorrect command: Have %v, want %v.\n", command, tc.wantCommand)
+			}
+			if steps != tc.wantSteps {
+				t.Errorf("Error incorrect steps: Have %v, want %v.\n", steps, tc.wantSteps)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Migrate(t *testing.T) {
+	cfg, err := loadConfig([]string{"-backend", backendPSQL, "down", "2"}, func(name string) string {
+		if name == "KGS_MIGRATE" {
+			return "true"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("Error loading config: %v.\n", err)
+	}
+	if !cfg.migrate {
+		t.Errorf("Error KGS_MIGRATE should enable migrations on startup.\n")
+	}
+	if len(cfg.args) != 2 || cfg.args[0] != "down" || cfg.args[1] != "2" {
+		t.Errorf("Error incorrect positional arguments: Have %v, want %v.\n", cfg.args, []string{"down", "2"})
+	}
+
+	if err := runMigrate(config{backend: backendMemory, args: []string{"up"}}); !errors.Is(err, ErrMigrateBackend) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrMigrateBackend)
+	}
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"KeyGenerationService/internal/identity"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrInvalidTLSConfig = errors.New("error invalid TLS configuration")
+	ErrClientNotAllowed = errors.New("error client certificate is not on the allow-list")
+	ErrInvalidTokens    = errors.New("error invalid token file")
+)
+
+// APIKeyMetadata is the gRPC metadata key an API key is sent in. Bearer tokens are sent in the authorization metadata.
+const APIKeyMetadata = "x-api-key"
+
+// TLSConfig describes the certificates of the gRPC server.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if set, turns on mutual TLS: every client must present a certificate signed by this CA.
+	ClientCAFile string
+	// AllowedClients, if set, only admits client certificates with one of these common names or DNS names.
+	AllowedClients []string
+}
+
+// ServerCredentials creates the transport credentials of the gRPC server from cfg.
+func ServerCredentials(cfg TLSConfig) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile == "" {
+		if len(cfg.AllowedClients) > 0 {
+			return nil, fmt.Errorf("%w: a client allow-list requires a client CA", ErrInvalidTLSConfig)
+		}
+		return credentials.NewTLS(tlsConfig), nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no certificate in %s", ErrInvalidTLSConfig, cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if len(cfg.AllowedClients) > 0 {
+		allowed := slices.Clone(cfg.AllowedClients)
+		tlsConfig.VerifyPeerCertificate = func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			for _, chain := range verifiedChains {
+				if isAllowed(chain[0], allowed) {
+					return nil
+				}
+			}
+			return ErrClientNotAllowed
+		}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// isAllowed checks whether the common name or one of the DNS names of cert is on the allow-list.
+func isAllowed(cert *x509.Certificate, allowed []string) bool {
+	if slices.Contains(allowed, cert.Subject.CommonName) {
+		return true
+	}
+	for _, name := range cert.DNSNames {
+		if slices.Contains(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator validates the bearer token or API key of every call, and stores the identity it belongs to with
+// identity.NewContext. Without tokens, calls are only authenticated by their mTLS client certificate, if any.
+type Authenticator struct {
+	// identities maps the SHA-256 hash of every token to its identity, so lookups don't leak tokens through timing.
+	identities map[[sha256.Size]byte]string
+}
+
+// NewAuthenticator creates an Authenticator accepting the given tokens, mapped to the identity they belong to.
+func NewAuthenticator(tokens map[string]string) *Authenticator {
+	a := &Authenticator{identities: make(map[[sha256.Size]byte]string, len(tokens))}
+	for token, id := range tokens {
+		a.identities[sha256.Sum256([]byte(token))] = id
+	}
+	return a
+}
+
+// LoadTokens reads a token file, where every line holds an identity and its token separated by whitespace.
+// Empty lines and lines starting with '#' are skipped.
+func LoadTokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTokens, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: line %d is not '<identity> <token>'", ErrInvalidTokens, n)
+		}
+		if _, ok := tokens[fields[1]]; ok {
+			return nil, fmt.Errorf("%w: line %d repeats a token", ErrInvalidTokens, n)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTokens, err)
+	}
+	return tokens, nil
+}
+
+// UnaryServerInterceptor authenticates every unary call.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates every stream when it's opened.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate returns ctx with the identity of the caller, or an Unauthenticated status.
+func (a *Authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	if len(a.identities) == 0 {
+		if id := certIdentity(ctx); id != "" {
+			return identity.NewContext(ctx, id), nil
+		}
+		return ctx, nil
+	}
+
+	token := tokenFromMetadata(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token or API key")
+	}
+	id, ok := a.identities[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token or API key")
+	}
+	return identity.NewContext(ctx, id), nil
+}
+
+// tokenFromMetadata returns the bearer token of the authorization metadata, or else the API key.
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if keys := md.Get(APIKeyMetadata); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// certIdentity returns the common name of the verified mTLS client certificate of the caller, if any.
+func certIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// authenticatedStream replaces the context of a grpc.ServerStream with an authenticated one.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
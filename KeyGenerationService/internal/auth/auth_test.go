@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"KeyGenerationService/internal/identity"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testCA is a certificate authority issuing local certificates for a test.
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	ca := &testCA{dir: t.TempDir()}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	ca.cert, ca.key, ca.file, _ = ca.issue(t, name, template, nil)
+	return ca
+}
+
+// issue creates a certificate from template signed by the CA, or self-signed if parent is nil,
+// and writes it and its key to PEM files.
+func (ca *testCA) issue(t *testing.T, name string, template *x509.Certificate, parent *testCA) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v.\n", err)
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %v.\n", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error marshalling key: %v.\n", err)
+	}
+	certFile := filepath.Join(ca.dir, name+".crt")
+	keyFile := filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, key, certFile, keyFile
+}
+
+// leaf issues a server or client certificate for name, returning its certificate and key files.
+func (ca *testCA) leaf(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	_, _, certFile, keyFile := ca.issue(t, name, template, ca)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Error writing %v: %v.\n", path, err)
+	}
+}
+
+// serve starts a health server with the given credentials and authenticator, and returns the listener and a channel
+// receiving the identity of every call.
+func serve(t *testing.T, creds credentials.TransportCredentials, a *Authenticator) (*bufconn.Listener, <-chan string) {
+	t.Helper()
+
+	ids := make(chan string, 10)
+	record := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ids <- identity.FromContext(ctx)
+		return handler(ctx, req)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(a.UnaryServerInterceptor(), record),
+		grpc.StreamInterceptor(a.StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return lis, ids
+}
+
+// check calls the health service over lis with the given client credentials.
+func check(t *testing.T, ctx context.Context, lis *bufconn.Listener, creds credentials.TransportCredentials) error {
+	t.Helper()
+
+	conn, err := grpc.Dial("localhost",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(creds),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing server: %v.\n", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func clientCredentials(t *testing.T, ca *testCA, certFile, keyFile string) credentials.TransportCredentials {
+	t.Helper()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatalf("Error loading client certificate: %v.\n", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(config)
+}
+
+func TestServerCredentials(t *testing.T) {
+	ca := newTestCA(t, "kgs-ca")
+	other := newTestCA(t, "other-ca")
+	serverCert, serverKey := ca.leaf(t, "localhost", x509.ExtKeyUsageServerAuth)
+	allowedCert, allowedKey := ca.leaf(t, "url-shortener", x509.ExtKeyUsageClientAuth)
+	deniedCert, deniedKey := ca.leaf(t, "intruder", x509.ExtKeyUsageClientAuth)
+	foreignCert, foreignKey := other.leaf(t, "url-shortener", x509.ExtKeyUsageClientAuth)
+
+	creds, err := ServerCredentials(TLSConfig{
+		CertFile:       serverCert,
+		KeyFile:        serverKey,
+		ClientCAFile:   ca.file,
+		AllowedClients: []string{"url-shortener"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating server credentials: %v.\n", err)
+	}
+	lis, ids := serve(t, creds, NewAuthenticator(nil))
+	ctx := context.Background()
+
+	// 1. An allowed client certificate is accepted, and its common name is the identity.
+	if err := check(t, ctx, lis, clientCredentials(t, ca, allowedCert, allowedKey)); err != nil {
+		t.Fatalf("Error calling with an allowed certificate: %v.\n", err)
+	}
+	if id := <-ids; id != "url-shortener" {
+		t.Errorf("Error incorrect identity: Have %q, want %q.\n", id, "url-shortener")
+	}
+
+	// 2. Clients without a certificate, off the allow-list or signed by another CA are rejected.
+	rejected := map[string]credentials.TransportCredentials{
+		"no certificate": clientCredentials(t, ca, "", ""),
+		"not allowed":    clientCredentials(t, ca, deniedCert, deniedKey),
+		"unknown CA":     clientCredentials(t, ca, foreignCert, foreignKey),
+		"plaintext":      insecure.NewCredentials(),
+	}
+	for name, clientCreds := range rejected {
+		if err := check(t, ctx, lis, clientCreds); status.Code(err) != codes.Unavailable {
+			t.Errorf("Error incorrect status code for %v: Have %v, want %v.\n", name, status.Code(err), codes.Unavailable)
+		}
+	}
+
+	// 3. Broken configurations are rejected.
+	invalid := []TLSConfig{
+		{CertFile: "missing.crt", KeyFile: "missing.key"},
+		{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: "missing.crt"},
+		{CertFile: serverCert, KeyFile: serverKey, AllowedClients: []string{"url-shortener"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := ServerCredentials(cfg); !errors.Is(err, ErrInvalidTLSConfig) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidTLSConfig)
+		}
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	ca := newTestCA(t, "kgs-ca")
+	serverCert, serverKey := ca.leaf(t, "localhost", x509.ExtKeyUsageServerAuth)
+	creds, err := ServerCredentials(TLSConfig{CertFile: serverCert, KeyFile: serverKey})
+	if err != nil {
+		t.Fatalf("Error creating server credentials: %v.\n", err)
+	}
+	lis, ids := serve(t, creds, NewAuthenticator(map[string]string{"secret-token": "url-shortener", "api-key": "batch-job"}))
+	clientCreds := clientCredentials(t, ca, "", "")
+
+	cases := []struct {
+		name string
+		md   metadata.MD
+		code codes.Code
+		id   string
+	}{
+		{"bearer token", metadata.Pairs("authorization", "Bearer secret-token"), codes.OK, "url-shortener"},
+		{"API key", metadata.Pairs(APIKeyMetadata, "api-key"), codes.OK, "batch-job"},
+		{"invalid token", metadata.Pairs("authorization", "Bearer wrong"), codes.Unauthenticated, ""},
+		{"missing token", metadata.Pairs(identity.MetadataKey, "url-shortener"), codes.Unauthenticated, ""},
+	}
+
+	for _, c := range cases {
+		ctx := metadata.NewOutgoingContext(context.Background(), c.md)
+		err := check(t, ctx, lis, clientCreds)
+		if status.Code(err) != c.code {
+			t.Errorf("Error incorrect status code for %v: Have %v, want %v.\n", c.name, status.Code(err), c.code)
+			continue
+		}
+		if c.code == codes.OK {
+			if id := <-ids; id != c.id {
+				t.Errorf("Error incorrect identity for %v: Have %q, want %q.\n", c.name, id, c.id)
+			}
+		}
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Error writing token file: %v.\n", err)
+		}
+		return path
+	}
+
+	tokens, err := LoadTokens(write("tokens", "# identity token\nurl-shortener secret-token\n\nbatch-job api-key\n"))
+	if err != nil {
+		t.Fatalf("Error loading tokens: %v.\n", err)
+	}
+	if len(tokens) != 2 || tokens["secret-token"] != "url-shortener" || tokens["api-key"] != "batch-job" {
+		t.Errorf("Error incorrect tokens: %v.\n", tokens)
+	}
+
+	invalid := []string{
+		write("fields", "url-shortener\n"),
+		write("repeated", "a token\nb token\n"),
+		filepath.Join(dir, "missing"),
+	}
+	for _, path := range invalid {
+		if _, err := LoadTokens(path); !errors.Is(err, ErrInvalidTokens) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidTokens)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// consumptionBuckets is the amount of buckets a consumptionMeter splits its window into.
+const consumptionBuckets = 60
+
+// consumptionMeter counts keys handed out over a sliding window, in fixed size time buckets.
+type consumptionMeter struct {
+	mu         sync.Mutex
+	window     time.Duration
+	bucketSize time.Duration
+	buckets    [consumptionBuckets]int
+	// last is the absolute number of the most recent bucket, every bucket after it is stale.
+	last    int64
+	created time.Time
+	now     func() time.Time
+}
+
+// newConsumptionMeter creates a consumptionMeter over the given window.
+func newConsumptionMeter(window time.Duration, now func() time.Time) *consumptionMeter {
+	m := &consumptionMeter{
+		window:     window,
+		bucketSize: max(window/consumptionBuckets, 1),
+		created:    now(),
+		now:        now,
+	}
+	m.last = m.bucket(m.created)
+	return m
+}
+
+// add records n consumed keys.
+func (m *consumptionMeter) add(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.advance()
+	m.buckets[b%consumptionBuckets] += n
+}
+
+// rate returns the average consumed keys per second over the window, or since creation if that is shorter.
+func (m *consumptionMeter) rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.advance()
+	elapsed := min(m.now().Sub(m.created), m.window)
+	if elapsed <= 0 {
+		return 0
+	}
+
+	var total int
+	for _, n := range m.buckets {
+		total += n
+	}
+	return float64(total) / elapsed.Seconds()
+}
+
+// advance clears the buckets that fell out of the window and returns the absolute number of the current bucket.
+// mu must be held.
+func (m *consumptionMeter) advance() int64 {
+	b := m.bucket(m.now())
+	if b-m.last >= consumptionBuckets {
+		m.buckets = [consumptionBuckets]int{}
+	} else {
+		for i := m.last + 1; i <= b; i++ {
+			m.buckets[i%consumptionBuckets] = 0
+		}
+	}
+	m.last = max(m.last, b)
+	return b
+}
+
+func (m *consumptionMeter) bucket(t time.Time) int64 {
+	return t.UnixNano() / int64(m.bucketSize)
+}
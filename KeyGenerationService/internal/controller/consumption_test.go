@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsumptionMeter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	m := newConsumptionMeter(time.Minute, clock)
+	if rate := m.rate(); rate != 0 {
+		t.Errorf("Error incorrect rate: Have %v, want %v.\n", rate, 0)
+	}
+
+	// 1. 30 keys over the first 30 seconds.
+	for i := 0; i < 30; i++ {
+		now = now.Add(time.Second)
+		m.add(1)
+	}
+	if rate := m.rate(); rate != 1 {
+		t.Errorf("Error incorrect rate: Have %v, want %v.\n", rate, 1)
+	}
+
+	// 2. 90 more keys at once, the window is now full.
+	now = now.Add(30 * time.Second)
+	m.add(90)
+	if rate := m.rate(); rate != 2 {
+		t.Errorf("Error incorrect rate: Have %v, want %v.\n", rate, 2)
+	}
+
+	// 3. Half a window later, the first 30 keys fell out of the window.
+	now = now.Add(30 * time.Second)
+	if rate := m.rate(); rate != 1.5 {
+		t.Errorf("Error incorrect rate: Have %v, want %v.\n", rate, 1.5)
+	}
+
+	// 4. Nothing consumed for a whole window.
+	now = now.Add(2 * time.Minute)
+	if rate := m.rate(); rate != 0 {
+		t.Errorf("Error incorrect rate: Have %v, want %v.\n", rate, 0)
+	}
+}
@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"KeyGenerationService/internal/identity"
 	"KeyGenerationService/internal/repository"
 	"context"
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +23,15 @@ var (
 	ErrInvalidKeyLength = errors.New("error cannot have key length equal or smaller than 0")
 	ErrInvalidPoolSize  = errors.New("error cannot have pool size smaller than 0")
 	ErrGetKeysError     = errors.New("error getting keys from database")
+	ErrInvalidWaterMark = errors.New("error cannot have negative low-water mark or high-water mark smaller than low-water mark")
+	ErrInvalidInterval  = errors.New("error cannot have interval equal or smaller than 0")
+	ErrInvalidTTL       = errors.New("error cannot have lease TTL equal or smaller than 0, or longer than a year")
+	ErrLeaseError       = errors.New("error leasing keys from database")
+	ErrNoPermutation    = errors.New("error remaining keys are only known when enumerating keys with a permutation")
+	ErrInvalidKeyCount  = errors.New("error cannot request zero or a negative amount of keys")
+	ErrInvalidAlias     = errors.New("error alias is too short, too long, contains characters outside the alias alphabet or is blocked")
+	ErrInvalidRules     = errors.New("error cannot have alias length equal or smaller than 0, max alias length smaller than alias length, or an empty alias alphabet")
+	ErrReserveError     = errors.New("error reserving key in database")
 )
 
 type KGSError struct {
@@ -29,36 +42,378 @@ func (e *KGSError) Error() string {
 	return e.Err.Error()
 }
 
+func (e *KGSError) Unwrap() error {
+	return e.Err
+}
+
+// MaxLeaseTTL is the longest time leased keys can stay unconfirmed.
+const MaxLeaseTTL = 365 * 24 * time.Hour
+
+// PostgreSQL has a default limit of 115 concurrent connections.
+// If connection(read/write goroutines) exceeded the limit,
+// it triggers the "FATAL: sorry, too many clients already" error, causing incoming connections to be rejected.
+const maxDatabaseConnections = 100
+
+// KeyLengthPolicy decides which keys are handed out once the key length has changed.
+type KeyLengthPolicy int
+
+const (
+	// DrainOldFirst hands out the remaining keys of older, shorter key lengths before keys of the current length.
+	DrainOldFirst KeyLengthPolicy = iota
+	// CurrentLengthOnly only hands out keys of the current key length, unused keys of older lengths stay reserved.
+	CurrentLengthOnly
+)
+
+// AliasRules decides which aliases clients can reserve as their own keys. They are independent of generated keys, so
+// aliases can be longer or contain characters generated keys never do.
+type AliasRules struct {
+	MinLength int
+	MaxLength int
+	// Alphabet contains all possible characters of an alias.
+	Alphabet string
+}
+
+// DefaultAliasRules accepts aliases of 3 to 32 letters, digits, '-' and '_'.
+var DefaultAliasRules = AliasRules{MinLength: 3, MaxLength: 32, Alphabet: LetterBytes + "-_"}
+
+// Validate checks whether the rules accept any alias at all.
+func (r AliasRules) Validate() error {
+	if r.MinLength <= 0 || r.MaxLength < r.MinLength || r.Alphabet == "" {
+		return ErrInvalidRules
+	}
+	return nil
+}
+
+// Valid checks whether alias has a valid length and only consists of the alias alphabet.
+func (r AliasRules) Valid(alias string) bool {
+	if len(alias) < r.MinLength || len(alias) > r.MaxLength {
+		return false
+	}
+	for i := 0; i < len(alias); i++ {
+		if strings.IndexByte(r.Alphabet, alias[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // KGS is the core for Key Generation Service.
 type KGS struct {
 	db repository.KGSDatabase
+	// keyLength is the length of newly generated keys, it can change while KGS is running.
+	keyLength atomic.Int64
+	keySource KeySource
+	policy    KeyLengthPolicy
+
+	// growthThreshold is the fraction of the key space that, once left, makes KGS switch to the next key length.
+	// Zero disables growing the key length.
+	growthThreshold float64
+	maxKeyLength    int
+
+	// permutation, if set, replaces keySource and enumerates the key space instead of drawing random keys.
+	permutation *Permutation
+	// unwritten holds reserved indexes per key length whose keys weren't written, because generation failed or was
+	// cancelled. They only live in memory, so they are lost for good when KGS stops.
+	unwrittenMu sync.Mutex
+	unwritten   map[int][]uint64
+
+	consumptionWindow time.Duration
+	consumption       *consumptionMeter
+
+	// auditLog, if set, records which client took, confirmed or released which keys.
+	auditLog *log.Logger
+
+	aliasRules AliasRules
+
+	// filter, if set, discards generated keys and rejects aliases it doesn't allow.
+	filter KeyFilter
+}
+
+// Option configures optional behaviour of KGS.
+type Option func(*KGS)
+
+// WithKeySource sets the KeySource used to generate keys. Defaults to FastKeySource.
+func WithKeySource(source KeySource) Option {
+	return func(k *KGS) {
+		k.keySource = source
+	}
+}
+
+// WithPermutation makes KGS enumerate the key space in the order of p instead of drawing random keys.
+// Every generated key is new without checking the database, and the remaining key space is known exactly.
+func WithPermutation(p *Permutation) Option {
+	return func(k *KGS) {
+		k.permutation = p
+	}
+}
+
+// WithConsumptionWindow sets how far back consumed keys are counted when projecting the key space exhaustion.
+// Defaults to an hour.
+func WithConsumptionWindow(window time.Duration) Option {
+	return func(k *KGS) {
+		k.consumptionWindow = window
+	}
+}
+
+// WithKeyLengthPolicy sets which keys are handed out after a key length change. Defaults to DrainOldFirst.
+func WithKeyLengthPolicy(policy KeyLengthPolicy) Option {
+	return func(k *KGS) {
+		k.policy = policy
+	}
+}
+
+// WithKeyLengthGrowth makes Replenish switch to the next key length, up to maxKeyLength,
+// when less than threshold (a fraction between 0 and 1) of the key space of the current length is left.
+func WithKeyLengthGrowth(threshold float64, maxKeyLength int) Option {
+	return func(k *KGS) {
+		k.growthThreshold = threshold
+		k.maxKeyLength = maxKeyLength
+	}
+}
+
+// WithAuditLog makes KGS record every handed out, leased, confirmed and released batch of keys to logger,
+// together with the identity of the client, as stored by identity.NewContext or derived from the gRPC call.
+func WithAuditLog(logger *log.Logger) Option {
+	return func(k *KGS) {
+		k.auditLog = logger
+	}
+}
+
+// WithAliasRules sets which aliases ReserveKey accepts. Defaults to DefaultAliasRules.
+func WithAliasRules(rules AliasRules) Option {
+	return func(k *KGS) {
+		k.aliasRules = rules
+	}
+}
+
+// WithKeyFilter discards generated keys that filter doesn't allow before they are written to the pool, and makes
+// ReserveKey reject such aliases.
+func WithKeyFilter(filter KeyFilter) Option {
+	return func(k *KGS) {
+		k.filter = filter
+	}
 }
 
 // New creates a new instance of KGS and generate keys concurrently to the database.
-func New(db repository.KGSDatabase, defaultPoolSize int, keyLength int) (*KGS, error) {
+// Generating the initial pool stops early when ctx is cancelled.
+func New(ctx context.Context, db repository.KGSDatabase, defaultPoolSize int, keyLength int, opts ...Option) (*KGS, error) {
 	if defaultPoolSize < 0 {
 		return nil, ErrInvalidPoolSize
 	}
 
-	// PostgreSQL has a default limit of 115 concurrent connections.
-	// If connection(read/write goroutines) exceeded the limit,
-	// it triggers the "FATAL: sorry, too many clients already" error, causing incoming connections to be rejected.
-	maxDatabaseConnections := 100
+	kgs := &KGS{
+		db:                db,
+		keySource:         FastKeySource{},
+		consumptionWindow: time.Hour,
+		aliasRules:        DefaultAliasRules,
+		unwritten:         make(map[int][]uint64),
+	}
+	kgs.keyLength.Store(int64(keyLength))
+	for _, opt := range opts {
+		opt(kgs)
+	}
+	if err := kgs.aliasRules.Validate(); err != nil {
+		return nil, err
+	}
+	// Enumerated keys can't grow beyond the key space the permutation covers.
+	if kgs.permutation != nil && kgs.growthThreshold > 0 && kgs.maxKeyLength > keyLength {
+		if _, err := Capacity(kgs.maxKeyLength); err != nil {
+			return nil, err
+		}
+	}
+	kgs.consumption = newConsumptionMeter(kgs.consumptionWindow, time.Now)
+
+	if err := kgs.generateKeys(ctx, defaultPoolSize); err != nil {
+		return nil, err
+	}
 
-	kgs := &KGS{db: db}
-	ctx := context.TODO()
+	return kgs, nil
+}
+
+// generateKeys generates amount new keys concurrently and writes them to the database.
+// Generation stops early when ctx is cancelled.
+func (k *KGS) generateKeys(ctx context.Context, amount int) error {
+	// The whole batch has the same length, even if the key length changes meanwhile.
+	keyLength := k.KeyLength()
+	if k.permutation != nil {
+		return k.enumerateKeys(ctx, keyLength, amount)
+	}
 
-	errChan := make(chan error)
-	doneChan := make(chan struct{})
+	return concurrently(ctx, amount, func(int) error {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			key, err := k.keySource.Key(keyLength)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidKeyLength) {
+					err = fmt.Errorf("%s: %w", "Key source error", err)
+				}
+				return err
+			}
+			if !k.allowed(key) {
+				continue
+			}
+			exist, err := k.db.KeyExist(ctx, key)
+			if err != nil && !errors.Is(err, repository.ErrKeyNotFound) {
+				return repoError(ErrRepoError, err)
+			}
+
+			if !exist {
+				if err := k.db.WriteKey(ctx, key); err != nil {
+					return repoError(ErrRepoError, err)
+				}
+				return nil
+			}
+		}
+	})
+}
+
+// enumerateKeys writes the keys at the next amount indexes of the permutation to the database. Indexes left
+// unwritten by an earlier failed or cancelled call are written first, the rest are reserved from the database.
+// Every index maps to a distinct key, so no existence check is needed.
+// Keys the filter doesn't allow are skipped, so fewer than amount keys may be written.
+func (k *KGS) enumerateKeys(ctx context.Context, keyLength int, amount int) error {
+	capacity, err := Capacity(keyLength)
+	if err != nil {
+		return err
+	}
+
+	indexes := k.takeUnwritten(keyLength, amount)
+	exhausted := false
+	if n := amount - len(indexes); n > 0 {
+		start, err := k.db.ReserveIndexes(ctx, keyLength, n)
+		if err != nil {
+			k.putUnwritten(keyLength, indexes)
+			return repoError(ErrRepoError, err)
+		}
+
+		// The last reservation may run past the end of the key space, only the part within it is generated.
+		available := 0
+		if start < capacity {
+			available = int(min(uint64(n), capacity-start))
+		}
+		for i := 0; i < available; i++ {
+			indexes = append(indexes, start+uint64(i))
+		}
+		exhausted = available < n
+	}
+
+	// Every call sets only its own element, and they are read once all calls are done.
+	written := make([]bool, len(indexes))
+	err = concurrently(ctx, len(indexes), func(i int) error {
+		key, err := k.permutation.KeyAt(keyLength, indexes[i])
+		if err != nil {
+			return err
+		}
+		if k.allowed(key) {
+			if err := k.db.WriteKey(ctx, key); err != nil {
+				return repoError(ErrRepoError, err)
+			}
+		}
+		written[i] = true
+		return nil
+	})
+	if err != nil {
+		var unwritten []uint64
+		for i, index := range indexes {
+			if !written[i] {
+				unwritten = append(unwritten, index)
+			}
+		}
+		k.putUnwritten(keyLength, unwritten)
+		return err
+	}
+
+	if exhausted {
+		return ErrKeySpaceExhausted
+	}
+	return nil
+}
+
+// Flush writes the keys of reserved indexes that failed or cancelled refills left unwritten. Indexes still unwritten
+// afterwards are lost once KGS stops, leaving holes in the key space, so their amount is logged.
+// Call it on shutdown, once Replenish has returned.
+func (k *KGS) Flush(ctx context.Context) error {
+	k.unwrittenMu.Lock()
+	lengths := make([]int, 0, len(k.unwritten))
+	for keyLength := range k.unwritten {
+		lengths = append(lengths, keyLength)
+	}
+	k.unwrittenMu.Unlock()
+
+	var flushErr error
+	lost := uint64(0)
+	for _, keyLength := range lengths {
+		if n := k.unwrittenCount(keyLength); n > 0 {
+			if err := k.enumerateKeys(ctx, keyLength, int(n)); err != nil && flushErr == nil {
+				flushErr = err
+			}
+		}
+		lost += k.unwrittenCount(keyLength)
+	}
+
+	if lost > 0 {
+		log.Printf("%d reserved key indexes couldn't be written and are lost.\n", lost)
+	}
+	return flushErr
+}
+
+// takeUnwritten removes up to n unwritten indexes of the given key length and returns them.
+func (k *KGS) takeUnwritten(keyLength int, n int) []uint64 {
+	k.unwrittenMu.Lock()
+	defer k.unwrittenMu.Unlock()
+
+	pending := k.unwritten[keyLength]
+	n = min(n, len(pending))
+	indexes := append([]uint64(nil), pending[len(pending)-n:]...)
+	k.unwritten[keyLength] = pending[:len(pending)-n]
+	return indexes
+}
+
+// putUnwritten keeps reserved indexes of the given key length whose keys weren't written, so they are written later.
+func (k *KGS) putUnwritten(keyLength int, indexes []uint64) {
+	if len(indexes) == 0 {
+		return
+	}
+	k.unwrittenMu.Lock()
+	defer k.unwrittenMu.Unlock()
+	k.unwritten[keyLength] = append(k.unwritten[keyLength], indexes...)
+}
+
+// unwrittenCount returns how many reserved indexes of the given key length are waiting to be written.
+func (k *KGS) unwrittenCount(keyLength int) uint64 {
+	k.unwrittenMu.Lock()
+	defer k.unwrittenMu.Unlock()
+	return uint64(len(k.unwritten[keyLength]))
+}
+
+// allowed checks whether key passes the filter, if there is one.
+func (k *KGS) allowed(key string) bool {
+	return k.filter == nil || k.filter.Allow(key)
+}
+
+// concurrently calls fn with 0 to n-1 from at most maxDatabaseConnections goroutines at once, and returns the first error.
+// Calls that haven't started yet are skipped once ctx is cancelled.
+func concurrently(ctx context.Context, n int, fn func(i int) error) error {
+	// Only the first error is kept, the rest of the goroutines give up without blocking.
+	errChan := make(chan error, 1)
+	sendErr := func(err error) {
+		select {
+		case errChan <- err:
+		default:
+		}
+	}
 
 	// Buffered semaphoreChan blocks goroutine from starting when channel is full.
 	// Acts as a pool that allows token to be acquired(put token in semaphore) or to be released(drain semaphore).
 	semaphoreChan := make(chan struct{}, maxDatabaseConnections)
 
 	var wg sync.WaitGroup
-	for i := 0; i < defaultPoolSize; i++ {
+	for i := 0; i < n; i++ {
 		wg.Add(1)
-		go func() {
+		go func(i int) {
 			defer wg.Done()
 			// Put token to semaphore when start goroutine.
 			semaphoreChan <- struct{}{}
@@ -67,41 +422,169 @@ func New(db repository.KGSDatabase, defaultPoolSize int, keyLength int) (*KGS, e
 				<-semaphoreChan
 			}()
 
-			for {
-				key, err := generateKey(keyLength)
-				if err != nil {
-					errChan <- ErrInvalidKeyLength
-					return
-				}
-				exist, err := kgs.db.KeyExist(ctx, key)
-				if err != nil && !errors.Is(err, repository.ErrKeyNotFound) {
-					errChan <- ErrRepoError
-					return
-				}
-
-				if !exist {
-					err = kgs.db.WriteKey(ctx, key)
-					if err != nil {
-						errChan <- ErrRepoError
-						return
-					}
-					break
-				}
+			if err := ctx.Err(); err != nil {
+				sendErr(err)
+				return
+			}
+			if err := fn(i); err != nil {
+				sendErr(err)
 			}
-		}()
+		}(i)
 	}
-
-	go func() {
-		wg.Wait()
-		doneChan <- struct{}{}
-	}()
+	wg.Wait()
 
 	select {
 	case err := <-errChan:
-		return nil, err
-	case <-doneChan:
-		return kgs, nil
+		return err
+	default:
+		return nil
+	}
+}
+
+// RemainingKeys returns how many keys of the current key length are left to be generated.
+// It is only known when keys are enumerated with a Permutation.
+func (k *KGS) RemainingKeys(ctx context.Context) (uint64, error) {
+	if k.permutation == nil {
+		return 0, ErrNoPermutation
 	}
+
+	_, remaining, err := k.remainingKeySpace(ctx, k.KeyLength(), nil)
+	return remaining, err
+}
+
+// remainingKeySpace returns the capacity of the key space of the given length, capped at math.MaxUint64,
+// and how much of it is left to be generated.
+// Without a permutation, what's left is estimated from stats, which are fetched if nil.
+func (k *KGS) remainingKeySpace(ctx context.Context, keyLength int, stats *repository.Stats) (uint64, uint64, error) {
+	capacity, err := Capacity(keyLength)
+	if errors.Is(err, ErrKeySpaceTooLarge) {
+		capacity = math.MaxUint64
+	} else if err != nil {
+		return 0, 0, err
+	}
+
+	var generated uint64
+	if k.permutation != nil {
+		reserved, err := k.db.ReserveIndexes(ctx, keyLength, 0)
+		if err != nil {
+			return 0, 0, repoError(ErrRepoError, err)
+		}
+		// Unwritten indexes are always within the key space, and are generated later.
+		generated = min(reserved, capacity) - k.unwrittenCount(keyLength)
+	} else {
+		if stats == nil {
+			dbStats, err := k.db.Stats(ctx)
+			if err != nil {
+				return 0, 0, repoError(ErrRepoError, err)
+			}
+			stats = &dbStats
+		}
+		// Every generated key is stored in one of the three states.
+		counts := stats.ByLength[keyLength]
+		generated = uint64(counts.Unused + counts.Leased + counts.Used)
+	}
+
+	if generated >= capacity {
+		return capacity, 0, nil
+	}
+	return capacity, capacity - generated, nil
+}
+
+// KeyLength returns the length of newly generated keys.
+func (k *KGS) KeyLength() int {
+	return int(k.keyLength.Load())
+}
+
+// SetKeyLength changes the length of newly generated keys without stopping KGS.
+// Keys of the previous length stay in the database, so they are never generated again,
+// and unused ones are handed out according to the KeyLengthPolicy.
+func (k *KGS) SetKeyLength(keyLength int) error {
+	if keyLength <= 0 {
+		return ErrInvalidKeyLength
+	}
+	if k.permutation != nil {
+		if _, err := Capacity(keyLength); err != nil {
+			return err
+		}
+	}
+
+	k.keyLength.Store(int64(keyLength))
+	return nil
+}
+
+// growKeyLength switches to the next key length when less than growthThreshold of the current key space is left.
+func (k *KGS) growKeyLength(ctx context.Context) error {
+	keyLength := k.KeyLength()
+	if k.growthThreshold <= 0 || keyLength >= k.maxKeyLength {
+		return nil
+	}
+
+	capacity, remaining, err := k.remainingKeySpace(ctx, keyLength, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", "Grow key length error", err)
+	}
+	if float64(remaining) >= k.growthThreshold*float64(capacity) {
+		return nil
+	}
+
+	if k.permutation != nil {
+		if _, err := Capacity(keyLength + 1); err != nil {
+			return fmt.Errorf("%s: %w", "Grow key length error", err)
+		}
+	}
+	if k.keyLength.CompareAndSwap(int64(keyLength), int64(keyLength+1)) {
+		log.Printf("Key space of length %d has %d of %d keys left, switching to key length %d.\n", keyLength, remaining, capacity, keyLength+1)
+	}
+	return nil
+}
+
+// Replenish supervises the key pool in the background.
+// Every interval it checks the amount of unused keys in the database, and whenever it falls below lowWaterMark,
+// new keys are generated until the pool is refilled to highWaterMark.
+// With WithKeyLengthGrowth, it also switches to the next key length once the current key space is nearly exhausted.
+// A failed refill is logged and retried on the next tick. Replenish blocks until ctx is cancelled.
+func (k *KGS) Replenish(ctx context.Context, lowWaterMark, highWaterMark int, interval time.Duration) error {
+	if lowWaterMark < 0 || highWaterMark < lowWaterMark {
+		return ErrInvalidWaterMark
+	}
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := k.growKeyLength(ctx); err != nil && ctx.Err() == nil {
+			log.Println(err)
+		}
+		if err := k.replenish(ctx, lowWaterMark, highWaterMark); err != nil && ctx.Err() == nil {
+			log.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// replenish refills the pool up to highWaterMark if the amount of available keys is below lowWaterMark.
+// Unused keys the KeyLengthPolicy never hands out don't count.
+func (k *KGS) replenish(ctx context.Context, lowWaterMark, highWaterMark int) error {
+	count, err := k.AvailableKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", "Replenish error", err)
+	}
+	if count >= lowWaterMark {
+		return nil
+	}
+
+	if err := k.generateKeys(ctx, highWaterMark-count); err != nil {
+		return fmt.Errorf("%s: %w", "Replenish error", err)
+	}
+	return nil
 }
 
 // generateKey generates the last four char in the shortenURL.
@@ -123,17 +606,243 @@ func generateKey(length int) (string, error) {
 
 // GetKeys fetches an array of keys with length requiredKeys from the Key Generation Service database.
 func (k *KGS) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	if requiredKeys <= 0 {
+		return nil, &KGSError{Err: fmt.Errorf("%s: %w", "Get keys error", ErrInvalidKeyCount)}
+	}
+
 	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	keys, err := k.db.GetKeys(ctrlCtx, requiredKeys)
+	var keys []string
+	var err error
+	if k.policy == CurrentLengthOnly {
+		keys, err = k.db.GetKeysAtLeast(ctrlCtx, k.KeyLength(), requiredKeys)
+	} else {
+		keys, err = k.db.GetKeys(ctrlCtx, requiredKeys)
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrKeyOOR) {
 			return nil, &KGSError{Err: fmt.Errorf("%s: %w.\n", "Get keys error", repository.ErrKeyOOR)}
 		}
 		// TODO: Log the unexpected error.
 		log.Println(err)
-		return nil, ErrGetKeysError
+		return nil, repoError(ErrGetKeysError, err)
 	}
+	k.consumption.add(len(keys))
+	k.audit(ctx, "got %d keys", len(keys))
 
 	return keys, nil
 }
+
+// LeaseKeys leases an array of keys with length requiredKeys from the Key Generation Service database.
+// Leased keys return to the pool unless they are confirmed within ttl.
+func (k *KGS) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	if requiredKeys <= 0 {
+		return repository.Lease{}, &KGSError{Err: fmt.Errorf("%s: %w", "Lease keys error", ErrInvalidKeyCount)}
+	}
+	if ttl <= 0 || ttl > MaxLeaseTTL {
+		return repository.Lease{}, &KGSError{Err: fmt.Errorf("%s: %w", "Lease keys error", ErrInvalidTTL)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	var lease repository.Lease
+	var err error
+	if k.policy == CurrentLengthOnly {
+		lease, err = k.db.LeaseKeysAtLeast(ctrlCtx, k.KeyLength(), requiredKeys, ttl)
+	} else {
+		lease, err = k.db.LeaseKeys(ctrlCtx, requiredKeys, ttl)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrKeyOOR) {
+			return repository.Lease{}, &KGSError{Err: fmt.Errorf("%s: %w", "Lease keys error", repository.ErrKeyOOR)}
+		}
+		log.Println(err)
+		return repository.Lease{}, repoError(ErrLeaseError, err)
+	}
+	k.consumption.add(len(lease.Keys))
+	k.audit(ctx, "leased %d keys under lease %s", len(lease.Keys), lease.ID)
+
+	return lease, nil
+}
+
+// AvailableKeys returns how many unused keys GetKeys and LeaseKeys can currently hand out.
+// Under CurrentLengthOnly, unused keys of older key lengths are not counted.
+func (k *KGS) AvailableKeys(ctx context.Context) (int, error) {
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	stats, err := k.db.Stats(ctrlCtx)
+	if err != nil {
+		log.Println(err)
+		return 0, repoError(ErrRepoError, err)
+	}
+
+	if k.policy != CurrentLengthOnly {
+		return stats.Unused, nil
+	}
+	var available int
+	for length, counts := range stats.ByLength {
+		if length >= k.KeyLength() {
+			available += counts.Unused
+		}
+	}
+	return available, nil
+}
+
+// ConfirmKeys marks leased keys as used, so they never return to the pool.
+func (k *KGS) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := leaseError("Confirm keys error", k.db.ConfirmKeys(ctrlCtx, leaseID, keys)); err != nil {
+		return err
+	}
+	k.audit(ctx, "confirmed %d keys of lease %s", len(keys), leaseID)
+	return nil
+}
+
+// ReleaseKeys returns leased keys to the pool. Every key left in the lease is released if keys is empty.
+func (k *KGS) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := leaseError("Release keys error", k.db.ReleaseKeys(ctrlCtx, leaseID, keys)); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		k.audit(ctx, "released lease %s", leaseID)
+	} else {
+		k.audit(ctx, "released %d keys of lease %s", len(keys), leaseID)
+	}
+	return nil
+}
+
+// ReserveKey hands out alias as a key chosen by the client, if it follows the AliasRules, passes the filter and was
+// never handed out. An alias that is still unused in the pool is taken out of it, so it's never handed out again.
+func (k *KGS) ReserveKey(ctx context.Context, alias string) error {
+	if !k.aliasRules.Valid(alias) || !k.allowed(alias) {
+		return &KGSError{Err: fmt.Errorf("%s: %w", "Reserve key error", ErrInvalidAlias)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := k.db.ReserveKey(ctrlCtx, alias); err != nil {
+		if errors.Is(err, repository.ErrKeyUsed) {
+			return &KGSError{Err: fmt.Errorf("%s: %w", "Reserve key error", repository.ErrKeyUsed)}
+		}
+		log.Println(err)
+		return repoError(ErrReserveError, err)
+	}
+	k.audit(ctx, "reserved alias %q", alias)
+
+	return nil
+}
+
+// audit records an action of the client calling ctx to the audit log, if there is one.
+func (k *KGS) audit(ctx context.Context, format string, args ...any) {
+	if k.auditLog == nil {
+		return
+	}
+	client := identity.FromContext(ctx)
+	if client == "" {
+		client = "anonymous"
+	}
+	k.auditLog.Printf("client %q "+format, append([]any{client}, args...)...)
+}
+
+// leaseError wraps the errors a client can act on in a KGSError, and logs the rest.
+func leaseError(prefix string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrLeaseNotFound), errors.Is(err, repository.ErrKeyNotLeased):
+		return &KGSError{Err: fmt.Errorf("%s: %w", prefix, err)}
+	default:
+		log.Println(err)
+		return repoError(ErrLeaseError, err)
+	}
+}
+
+// repoError returns ctrlErr for a failed repository call, wrapping err as well if the call was cancelled or timed out.
+func repoError(ctrlErr error, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ctrlErr, err)
+	}
+	return ctrlErr
+}
+
+// ReapLeases returns the keys of expired leases to the pool every interval.
+// A failed sweep is logged and retried on the next tick. ReapLeases blocks until ctx is cancelled.
+func (k *KGS) ReapLeases(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if _, err := k.db.ExpireLeases(ctx); err != nil && ctx.Err() == nil {
+			log.Println(err)
+		}
+	}
+}
+
+// PoolStats is a snapshot of the key pool and the key space of the current key length.
+type PoolStats struct {
+	UnusedKeys int
+	LeasedKeys int
+	UsedKeys   int
+
+	KeyLength int
+	// Capacity is len(LetterBytes) ^ KeyLength, capped at math.MaxUint64.
+	Capacity uint64
+	// RemainingKeys is how many keys of the key space are left to be generated.
+	RemainingKeys uint64
+
+	// ConsumptionRate is the average amount of keys handed out per second over the consumption window.
+	ConsumptionRate float64
+	// TimeToExhaustion projects when the key space runs out at ConsumptionRate, it is negative without consumption.
+	TimeToExhaustion time.Duration
+}
+
+// PoolStats reports how many keys are unused, leased and used, how much of the key space is left,
+// and when it is projected to run out based on recent consumption.
+func (k *KGS) PoolStats(ctx context.Context) (PoolStats, error) {
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	dbStats, err := k.db.Stats(ctrlCtx)
+	if err != nil {
+		log.Println(err)
+		return PoolStats{}, repoError(ErrRepoError, err)
+	}
+
+	keyLength := k.KeyLength()
+	stats := PoolStats{
+		UnusedKeys:       dbStats.Unused,
+		LeasedKeys:       dbStats.Leased,
+		UsedKeys:         dbStats.Used,
+		KeyLength:        keyLength,
+		ConsumptionRate:  k.consumption.rate(),
+		TimeToExhaustion: -1,
+	}
+
+	stats.Capacity, stats.RemainingKeys, err = k.remainingKeySpace(ctrlCtx, keyLength, &dbStats)
+	if err != nil {
+		return PoolStats{}, err
+	}
+
+	// Keys already in the pool are handed out before the key space runs dry.
+	if stats.ConsumptionRate > 0 {
+		left := float64(stats.RemainingKeys) + float64(stats.UnusedKeys)
+		stats.TimeToExhaustion = time.Duration(math.MaxInt64)
+		if nanos := left / stats.ConsumptionRate * float64(time.Second); nanos < math.MaxInt64 {
+			stats.TimeToExhaustion = time.Duration(nanos)
+		}
+	}
+
+	return stats, nil
+}
@@ -1,11 +1,18 @@
 package controller
 
 import (
+	"KeyGenerationService/internal/identity"
+	"KeyGenerationService/internal/repository"
 	"KeyGenerationService/internal/repository/memory"
 	"KeyGenerationService/internal/repository/psql"
+	"bytes"
 	"context"
 	"errors"
+	"log"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -19,25 +26,55 @@ func TestNew(t *testing.T) {
 			t.Errorf("Error creating database: %v.\n", err)
 		}
 
-		kgs, err := New(db, 100000, 4)
+		kgs, err := New(context.Background(), db, 100000, 4)
 		if err != nil || kgs == nil {
 			t.Errorf("Error creating controller: %v.\n", err)
 		}
 	})
 
-	t.Run("Test relational database", func(t *testing.T) {
-		db, err := psql.New("URLShortenerUser", "URLShortenerPassword", "KeyGenerationService")
+	t.Run("Test secure key source", func(t *testing.T) {
+		db, err := memory.New()
+		if err != nil {
+			t.Errorf("Error creating instance DB.\n")
+		}
+
+		kgs, err := New(context.Background(), db, 100, 4, WithKeySource(SecureKeySource{}))
+		if err != nil || kgs == nil {
+			t.Errorf("Error creating controller: %v.\n", err)
+		}
+		if count, _ := db.KeyCount(context.Background()); count != 100 {
+			t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, 100)
+		}
+	})
+
+	t.Run("Test cancelled context", func(t *testing.T) {
+		db, err := memory.New()
 		if err != nil {
 			t.Errorf("Error creating instance DB.\n")
 		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := New(ctx, db, 100, 4); !errors.Is(err, context.Canceled) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, context.Canceled)
+		}
+	})
+
+	t.Run("Test relational database", func(t *testing.T) {
+		// Runs against the same testing database as the psql tests.
+		dsn := os.Getenv("KGS_TEST_PSQL_DSN")
+		if dsn == "" {
+			t.Skip("KGS_TEST_PSQL_DSN isn't set.")
+		}
+		db, err := psql.Open(context.Background(), psql.Config{DSN: dsn})
 		if err != nil {
-			t.Errorf("Error creating database: %v.\n", err)
+			t.Fatalf("Error creating database: %v.\n", err)
 		}
 
 		defaultPoolSizeCases := []int{-1, 0, 1000}
 
 		for _, defaultPoolSize := range defaultPoolSizeCases {
-			kgs, err := New(db, defaultPoolSize, 4)
+			kgs, err := New(context.Background(), db, defaultPoolSize, 4)
 			if err != nil {
 				if defaultPoolSize < 0 && !errors.Is(err, ErrInvalidPoolSize) {
 					t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidPoolSize)
@@ -62,7 +99,7 @@ func TestKGS_GetKeys(t *testing.T) {
 	}
 
 	defaultPoolSize := 100
-	kgs, err := New(db, defaultPoolSize, 4)
+	kgs, err := New(ctx, db, defaultPoolSize, 4)
 	if err != nil || kgs == nil {
 		t.Errorf("Error creating controller: %v.\n", err)
 	}
@@ -74,6 +111,8 @@ func TestKGS_GetKeys(t *testing.T) {
 			var ctrlError *KGSError
 			if (requiredKeys <= 0 || requiredKeys > defaultPoolSize) && !errors.As(err, &ctrlError) {
 				t.Errorf("Error incorrect error: %v.\n", err)
+			} else if requiredKeys <= 0 && !errors.Is(err, ErrInvalidKeyCount) {
+				t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidKeyCount)
 			} else if 0 < requiredKeys && requiredKeys <= defaultPoolSize {
 				t.Errorf("Error getting keys from database: %v.\n", err)
 			}
@@ -85,6 +124,26 @@ func TestKGS_GetKeys(t *testing.T) {
 	}
 }
 
+func TestKGS_GetKeys_ContextCancellation(t *testing.T) {
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	kgs, err := New(context.Background(), db, 10, 4)
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = kgs.GetKeys(ctx, 1)
+	if !errors.Is(err, ErrGetKeysError) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, context.Canceled)
+	}
+}
+
 func Test_generateKey(t *testing.T) {
 	lengthCases := []int{-1, 0, 4}
 
@@ -105,3 +164,619 @@ func Test_generateKey(t *testing.T) {
 		}
 	}
 }
+
+func TestKGS_Replenish(t *testing.T) {
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	kgs, err := New(context.Background(), db, 20, 4)
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	t.Run("Test invalid arguments", func(t *testing.T) {
+		ctx := context.Background()
+		if err := kgs.Replenish(ctx, -1, 10, time.Millisecond); !errors.Is(err, ErrInvalidWaterMark) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidWaterMark)
+		}
+		if err := kgs.Replenish(ctx, 10, 5, time.Millisecond); !errors.Is(err, ErrInvalidWaterMark) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidWaterMark)
+		}
+		if err := kgs.Replenish(ctx, 5, 10, 0); !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidInterval)
+		}
+	})
+
+	t.Run("Test refill below low-water mark", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Drain the pool below the low-water mark.
+		if _, err := kgs.GetKeys(ctx, 15); err != nil {
+			t.Fatalf("Error getting keys from database: %v.\n", err)
+		}
+
+		lowWaterMark, highWaterMark := 10, 50
+		done := make(chan error)
+		go func() {
+			done <- kgs.Replenish(ctx, lowWaterMark, highWaterMark, 10*time.Millisecond)
+		}()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			count, err := db.KeyCount(ctx)
+			if err != nil {
+				t.Fatalf("Error counting keys: %v.\n", err)
+			}
+			if count >= highWaterMark {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Error pool isn't refilled: Have %v, want %v.\n", count, highWaterMark)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// Pool above the low-water mark shouldn't be refilled.
+		if _, err := kgs.GetKeys(ctx, 5); err != nil {
+			t.Fatalf("Error getting keys from database: %v.\n", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		count, _ := db.KeyCount(ctx)
+		if count != highWaterMark-5 {
+			t.Errorf("Error pool above low-water mark is refilled: Have %v, want %v.\n", count, highWaterMark-5)
+		}
+
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Error stopping replenisher: %v.\n", err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Error replenisher doesn't stop after context is cancelled.\n")
+		}
+	})
+}
+
+func TestKGS_Replenish_CurrentLengthOnly(t *testing.T) {
+	ctx := context.Background()
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	kgs, err := New(ctx, db, 20, 4, WithKeyLengthPolicy(CurrentLengthOnly))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+	if err := kgs.SetKeyLength(5); err != nil {
+		t.Fatalf("Error setting key length: %v.\n", err)
+	}
+
+	// Unused keys of the old length are never handed out, so they don't keep the pool from being refilled,
+	// neither after the switch nor once the keys of the new length are drained.
+	for i := 0; i < 2; i++ {
+		if err := kgs.replenish(ctx, 10, 30); err != nil {
+			t.Fatalf("Error replenishing: %v.\n", err)
+		}
+		if available, err := kgs.AvailableKeys(ctx); err != nil || available != 30 {
+			t.Errorf("Error incorrect available keys: Have %v, want %v (%v).\n", available, 30, err)
+		}
+
+		keys, err := kgs.GetKeys(ctx, 30)
+		if err != nil {
+			t.Fatalf("Error getting keys: %v.\n", err)
+		}
+		for _, key := range keys {
+			if len(key) != 5 {
+				t.Errorf("Error incorrect key length: Have %v, want %v.\n", len(key), 5)
+			}
+		}
+	}
+}
+
+func TestKGS_LeaseKeys(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	defaultPoolSize := 100
+	kgs, err := New(ctx, db, defaultPoolSize, 4)
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	ttlCases := []time.Duration{-time.Second, 0, MaxLeaseTTL + time.Second, time.Minute}
+	for _, ttl := range ttlCases {
+		lease, err := kgs.LeaseKeys(ctx, 10, ttl)
+		if err != nil {
+			if (ttl > 0 && ttl <= MaxLeaseTTL) || !errors.Is(err, ErrInvalidTTL) {
+				t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidTTL)
+			}
+			continue
+		}
+		if len(lease.Keys) != 10 {
+			t.Errorf("Error leased keys length is incorrect: Have %v, want %v.\n", len(lease.Keys), 10)
+		}
+
+		var ctrlError *KGSError
+		err = kgs.ConfirmKeys(ctx, "unknown", lease.Keys)
+		if !errors.As(err, &ctrlError) || !errors.Is(err, repository.ErrLeaseNotFound) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrLeaseNotFound)
+		}
+		if err := kgs.ConfirmKeys(ctx, lease.ID, lease.Keys[:5]); err != nil {
+			t.Errorf("Error confirming keys: %v.\n", err)
+		}
+		if err := kgs.ReleaseKeys(ctx, lease.ID, nil); err != nil {
+			t.Errorf("Error releasing keys: %v.\n", err)
+		}
+	}
+
+	if count, _ := db.KeyCount(ctx); count != defaultPoolSize-5 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, defaultPoolSize-5)
+	}
+}
+
+func TestKGS_ReapLeases(t *testing.T) {
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	kgs, err := New(context.Background(), db, 10, 4)
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := kgs.LeaseKeys(ctx, 10, time.Millisecond); err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- kgs.ReapLeases(ctx, 5*time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for count, _ := db.KeyCount(ctx); count != 10; count, _ = db.KeyCount(ctx) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Error expired lease isn't returned to the pool: Have %v, want %v.\n", count, 10)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Error stopping lease reaper: %v.\n", err)
+	}
+}
+
+func TestKGS_Permutation(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error creating permutation: %v.\n", err)
+	}
+
+	// Key length 2 has 3844 keys.
+	keyLength, defaultPoolSize := 2, 3000
+	kgs, err := New(ctx, db, defaultPoolSize, keyLength, WithPermutation(p))
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	if count, _ := db.KeyCount(ctx); count != defaultPoolSize {
+		t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, defaultPoolSize)
+	}
+	remaining, err := kgs.RemainingKeys(ctx)
+	if err != nil {
+		t.Errorf("Error getting remaining keys: %v.\n", err)
+	}
+	if remaining != 3844-uint64(defaultPoolSize) {
+		t.Errorf("Error incorrect remaining keys: Have %v, want %v.\n", remaining, 3844-defaultPoolSize)
+	}
+
+	// Generating past the end of the key space fills what's left, then reports exhaustion.
+	if err := kgs.generateKeys(ctx, 1000); !errors.Is(err, ErrKeySpaceExhausted) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrKeySpaceExhausted)
+	}
+	if count, _ := db.KeyCount(ctx); count != 3844 {
+		t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, 3844)
+	}
+	if remaining, _ := kgs.RemainingKeys(ctx); remaining != 0 {
+		t.Errorf("Error incorrect remaining keys: Have %v, want %v.\n", remaining, 0)
+	}
+
+	// Remaining keys aren't known for random keys.
+	random, _ := New(ctx, db, 0, keyLength)
+	if _, err := random.RemainingKeys(ctx); !errors.Is(err, ErrNoPermutation) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrNoPermutation)
+	}
+}
+
+// failingDB fails every WriteKey once writesLeft keys were written.
+type failingDB struct {
+	*memory.InMemoryDB
+	writesLeft atomic.Int64
+}
+
+func (f *failingDB) WriteKey(ctx context.Context, key string) error {
+	if f.writesLeft.Add(-1) < 0 {
+		return errors.New("write failed")
+	}
+	return f.InMemoryDB.WriteKey(ctx, key)
+}
+
+func TestKGS_Permutation_Unwritten(t *testing.T) {
+	ctx := context.Background()
+
+	mem, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+	db := &failingDB{InMemoryDB: mem}
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error creating permutation: %v.\n", err)
+	}
+	kgs, err := New(ctx, db, 0, 2, WithPermutation(p))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	// 1. Indexes whose keys failed to be written still count as remaining key space.
+	db.writesLeft.Store(100)
+	if err := kgs.generateKeys(ctx, 500); !errors.Is(err, ErrRepoError) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrRepoError)
+	}
+	if remaining, _ := kgs.RemainingKeys(ctx); remaining != 3844-100 {
+		t.Errorf("Error incorrect remaining keys: Have %v, want %v.\n", remaining, 3844-100)
+	}
+
+	// 2. They are written before new indexes are reserved.
+	db.writesLeft.Store(1000)
+	if err := kgs.generateKeys(ctx, 400); err != nil {
+		t.Errorf("Error generating keys: %v.\n", err)
+	}
+	if count, _ := db.KeyCount(ctx); count != 500 {
+		t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, 500)
+	}
+	if reserved, _ := db.ReserveIndexes(ctx, 2, 0); reserved != 500 {
+		t.Errorf("Error incorrect reserved indexes: Have %v, want %v.\n", reserved, 500)
+	}
+	if remaining, _ := kgs.RemainingKeys(ctx); remaining != 3844-500 {
+		t.Errorf("Error incorrect remaining keys: Have %v, want %v.\n", remaining, 3844-500)
+	}
+
+	// 3. Flush writes them without reserving new indexes, and keeps them if writing fails again.
+	db.writesLeft.Store(50)
+	if err := kgs.generateKeys(ctx, 100); !errors.Is(err, ErrRepoError) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrRepoError)
+	}
+	db.writesLeft.Store(0)
+	if err := kgs.Flush(ctx); !errors.Is(err, ErrRepoError) || kgs.unwrittenCount(2) != 50 {
+		t.Errorf("Error incorrect flush: Have %v and %v unwritten, want %v and %v unwritten.\n", err, kgs.unwrittenCount(2), ErrRepoError, 50)
+	}
+	db.writesLeft.Store(1000)
+	if err := kgs.Flush(ctx); err != nil || kgs.unwrittenCount(2) != 0 {
+		t.Errorf("Error incorrect flush: Have %v and %v unwritten, want no error and %v unwritten.\n", err, kgs.unwrittenCount(2), 0)
+	}
+	if count, _ := db.KeyCount(ctx); count != 600 {
+		t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, 600)
+	}
+	if reserved, _ := db.ReserveIndexes(ctx, 2, 0); reserved != 600 {
+		t.Errorf("Error incorrect reserved indexes: Have %v, want %v.\n", reserved, 600)
+	}
+}
+
+func TestKGS_PoolStats(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	keyLength, defaultPoolSize := 2, 100
+	kgs, err := New(ctx, db, defaultPoolSize, keyLength)
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	// 1. Nothing consumed yet.
+	stats, err := kgs.PoolStats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting pool stats: %v.\n", err)
+	}
+	if stats.UnusedKeys != defaultPoolSize || stats.UsedKeys != 0 || stats.LeasedKeys != 0 {
+		t.Errorf("Error incorrect key counts: %+v.\n", stats)
+	}
+	if stats.KeyLength != keyLength || stats.Capacity != 3844 || stats.RemainingKeys != 3844-uint64(defaultPoolSize) {
+		t.Errorf("Error incorrect key space: %+v.\n", stats)
+	}
+	if stats.ConsumptionRate != 0 || stats.TimeToExhaustion >= 0 {
+		t.Errorf("Error incorrect projection without consumption: %+v.\n", stats)
+	}
+
+	// 2. Consume keys, exhaustion is projected.
+	if _, err := kgs.GetKeys(ctx, 30); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	if _, err := kgs.LeaseKeys(ctx, 20, time.Minute); err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	stats, err = kgs.PoolStats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting pool stats: %v.\n", err)
+	}
+	if stats.UnusedKeys != 50 || stats.UsedKeys != 30 || stats.LeasedKeys != 20 {
+		t.Errorf("Error incorrect key counts: %+v.\n", stats)
+	}
+	if stats.ConsumptionRate <= 0 || stats.TimeToExhaustion <= 0 {
+		t.Errorf("Error incorrect projection with consumption: %+v.\n", stats)
+	}
+}
+
+func TestKGS_SetKeyLength(t *testing.T) {
+	ctx := context.Background()
+
+	policies := map[string]KeyLengthPolicy{"drain old first": DrainOldFirst, "current length only": CurrentLengthOnly}
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			db, err := memory.New()
+			if err != nil {
+				t.Errorf("Error creating instance DB.\n")
+			}
+
+			kgs, err := New(ctx, db, 10, 4, WithKeyLengthPolicy(policy))
+			if err != nil || kgs == nil {
+				t.Fatalf("Error creating controller: %v.\n", err)
+			}
+
+			lengthCases := []int{-1, 0, 5}
+			for _, keyLength := range lengthCases {
+				err := kgs.SetKeyLength(keyLength)
+				if keyLength <= 0 && !errors.Is(err, ErrInvalidKeyLength) {
+					t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidKeyLength)
+				}
+			}
+			if kgs.KeyLength() != 5 {
+				t.Fatalf("Error incorrect key length: Have %v, want %v.\n", kgs.KeyLength(), 5)
+			}
+			if err := kgs.generateKeys(ctx, 10); err != nil {
+				t.Fatalf("Error generating keys: %v.\n", err)
+			}
+
+			wantAvailable := 20
+			if policy == CurrentLengthOnly {
+				wantAvailable = 10
+			}
+			if available, err := kgs.AvailableKeys(ctx); err != nil || available != wantAvailable {
+				t.Errorf("Error incorrect available keys: Have %v, want %v (%v).\n", available, wantAvailable, err)
+			}
+
+			keys, err := kgs.GetKeys(ctx, 10)
+			if err != nil {
+				t.Fatalf("Error getting keys: %v.\n", err)
+			}
+			want := 4
+			if policy == CurrentLengthOnly {
+				want = 5
+			}
+			for _, key := range keys {
+				if len(key) != want {
+					t.Errorf("Error incorrect key length: Have %v, want %v.\n", len(key), want)
+				}
+			}
+		})
+	}
+}
+
+func TestKGS_growKeyLength(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error creating permutation: %v.\n", err)
+	}
+
+	// Key length 1 has 62 keys, grow once less than half of them is left.
+	kgs, err := New(ctx, db, 20, 1, WithPermutation(p), WithKeyLengthGrowth(0.5, 2))
+	if err != nil || kgs == nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	if err := kgs.growKeyLength(ctx); err != nil || kgs.KeyLength() != 1 {
+		t.Errorf("Error key length shouldn't grow: Have %v, want %v (%v).\n", kgs.KeyLength(), 1, err)
+	}
+
+	if err := kgs.generateKeys(ctx, 20); err != nil {
+		t.Fatalf("Error generating keys: %v.\n", err)
+	}
+	if err := kgs.growKeyLength(ctx); err != nil || kgs.KeyLength() != 2 {
+		t.Errorf("Error key length should grow: Have %v, want %v (%v).\n", kgs.KeyLength(), 2, err)
+	}
+
+	// The maximum key length is never exceeded.
+	if err := kgs.generateKeys(ctx, 3000); err != nil {
+		t.Fatalf("Error generating keys: %v.\n", err)
+	}
+	if err := kgs.growKeyLength(ctx); err != nil || kgs.KeyLength() != 2 {
+		t.Errorf("Error key length shouldn't grow: Have %v, want %v (%v).\n", kgs.KeyLength(), 2, err)
+	}
+
+	// A maximum key length beyond the key space of the permutation is rejected up front.
+	if _, err := New(ctx, db, 0, 1, WithPermutation(p), WithKeyLengthGrowth(0.5, 11)); !errors.Is(err, ErrKeySpaceTooLarge) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrKeySpaceTooLarge)
+	}
+}
+
+func TestKGS_AuditLog(t *testing.T) {
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	var buf bytes.Buffer
+	kgs, err := New(context.Background(), db, 20, 4, WithAuditLog(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	ctx := identity.NewContext(context.Background(), "url-shortener")
+	if _, err := kgs.GetKeys(ctx, 3); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	lease, err := kgs.LeaseKeys(context.Background(), 2, time.Minute)
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	if err := kgs.ReleaseKeys(ctx, lease.ID, nil); err != nil {
+		t.Fatalf("Error releasing keys: %v.\n", err)
+	}
+	// Failed calls aren't audited.
+	_ = kgs.ConfirmKeys(ctx, lease.ID, lease.Keys)
+
+	want := "client \"url-shortener\" got 3 keys\n" +
+		"client \"anonymous\" leased 2 keys under lease " + lease.ID + "\n" +
+		"client \"url-shortener\" released lease " + lease.ID + "\n"
+	if buf.String() != want {
+		t.Errorf("Error incorrect audit log: Have %q, want %q.\n", buf.String(), want)
+	}
+}
+
+func TestKGS_ReserveKey(t *testing.T) {
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+
+	// 1. Alias rules are validated.
+	rulesCases := []AliasRules{{0, 5, "abc"}, {5, 4, "abc"}, {3, 5, ""}}
+	for _, rules := range rulesCases {
+		if _, err := New(context.Background(), db, 0, 4, WithAliasRules(rules)); !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidRules)
+		}
+	}
+
+	kgs, err := New(context.Background(), db, 20, 4, WithAliasRules(AliasRules{MinLength: 4, MaxLength: 8, Alphabet: LetterBytes + "."}))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+	ctx := context.Background()
+
+	// 2. Only aliases following the rules and never handed out are reserved, unused keys of the pool included.
+	var unused string
+	db.Keys.Range(func(key, _ any) bool {
+		unused = key.(string)
+		return false
+	})
+	cases := []struct {
+		alias string
+		want  error
+	}{
+		{"launch", nil},
+		{"go.dev", nil},
+		{unused, nil},
+		{"launch", repository.ErrKeyUsed},
+		{"abc", ErrInvalidAlias},
+		{"launch-day", ErrInvalidAlias},
+		{"launch_", ErrInvalidAlias},
+	}
+	for _, c := range cases {
+		err := kgs.ReserveKey(ctx, c.alias)
+		var kgsErr *KGSError
+		if !errors.Is(err, c.want) || (c.want != nil && !errors.As(err, &kgsErr)) {
+			t.Errorf("Error incorrect error reserving %v: Have %v, want %v.\n", c.alias, err, c.want)
+		}
+	}
+
+	// 3. A reserved key of the pool is never handed out.
+	keys, err := kgs.GetKeys(ctx, 19)
+	if err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	for _, key := range keys {
+		if key == unused {
+			t.Errorf("Error reserved key %v is handed out.\n", key)
+		}
+	}
+}
+
+func TestKGS_KeyFilter(t *testing.T) {
+	ctx := context.Background()
+	// Key length 1 has 62 keys, 8 of them are blocked: a, A, 4, b, B, 8, c and C.
+	filter := NewBlocklist([]string{"a", "b"}, []string{"c", "admin"})
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error creating permutation: %v.\n", err)
+	}
+
+	cases := []struct {
+		name string
+		opts []Option
+	}{
+		{"random", []Option{WithKeyFilter(filter)}},
+		{"permutation", []Option{WithKeyFilter(filter), WithPermutation(p)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, err := memory.New()
+			if err != nil {
+				t.Errorf("Error creating instance DB.\n")
+			}
+			kgs, err := New(ctx, db, 40, 1, c.opts...)
+			if err != nil {
+				t.Fatalf("Error creating controller: %v.\n", err)
+			}
+
+			// 1. Blocked keys are never written to the pool. Enumerating skips them, so the pool may hold fewer keys.
+			count, _ := db.KeyCount(ctx)
+			keys, err := kgs.GetKeys(ctx, count)
+			if err != nil {
+				t.Fatalf("Error getting keys: %v.\n", err)
+			}
+			for _, key := range keys {
+				if !filter.Allow(key) {
+					t.Errorf("Error blocked key %v is handed out.\n", key)
+				}
+			}
+
+			// 2. Blocked aliases can't be reserved.
+			if err := kgs.ReserveKey(ctx, "admin"); !errors.Is(err, ErrInvalidAlias) {
+				t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidAlias)
+			}
+		})
+	}
+
+	// 3. Enumerating the whole key space writes only the allowed keys.
+	db, err := memory.New()
+	if err != nil {
+		t.Errorf("Error creating instance DB.\n")
+	}
+	kgs, err := New(ctx, db, 0, 1, WithKeyFilter(filter), WithPermutation(p))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+	if err := kgs.generateKeys(ctx, 62); err != nil {
+		t.Errorf("Error generating keys: %v.\n", err)
+	}
+	if count, _ := db.KeyCount(ctx); count != 54 {
+		t.Errorf("Error incorrect pool size: Have %v, want %v.\n", count, 54)
+	}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrInvalidWordList = errors.New("error reading word list")
+
+// KeyFilter decides which generated keys may be written to the pool. Keys it rejects are discarded.
+type KeyFilter interface {
+	Allow(key string) bool
+}
+
+// DefaultReservedPaths are paths a key must never be equal to, as they are likely taken by the service itself.
+var DefaultReservedPaths = []string{"api", "admin", "help", "login", "logout", "static", "assets", "health", "metrics"}
+
+// leetspeak maps digits and letters that look alike to the same letter, so "b4d" and "bad" normalize the same way.
+var leetspeak = strings.NewReplacer(
+	"0", "o",
+	"1", "i", "l", "i",
+	"2", "z",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"6", "g", "9", "g",
+	"7", "t",
+	"8", "b",
+)
+
+// normalize lowercases s and replaces look-alike characters with the letter they stand for.
+func normalize(s string) string {
+	return leetspeak.Replace(strings.ToLower(s))
+}
+
+// Blocklist rejects keys that contain a blocked word anywhere, ignoring case and leetspeak, and keys that are
+// exactly one of the reserved paths, ignoring case.
+type Blocklist struct {
+	// words are normalized.
+	words    []string
+	reserved map[string]struct{}
+}
+
+// NewBlocklist creates a Blocklist of the given words and reserved paths. Empty entries are skipped.
+func NewBlocklist(words []string, reserved []string) *Blocklist {
+	b := &Blocklist{reserved: make(map[string]struct{}, len(reserved))}
+	for _, word := range words {
+		if word = normalize(strings.TrimSpace(word)); word != "" {
+			b.words = append(b.words, word)
+		}
+	}
+	for _, path := range reserved {
+		if path = strings.ToLower(strings.TrimSpace(path)); path != "" {
+			b.reserved[path] = struct{}{}
+		}
+	}
+	return b
+}
+
+// Allow checks whether key neither contains a blocked word nor is a reserved path.
+func (b *Blocklist) Allow(key string) bool {
+	if _, ok := b.reserved[strings.ToLower(key)]; ok {
+		return false
+	}
+	key = normalize(key)
+	for _, word := range b.words {
+		if strings.Contains(key, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadWordList reads a word list file with one word per line. Empty lines and lines starting with '#' are skipped.
+func LoadWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWordList, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWordList, err)
+	}
+	return words, nil
+}
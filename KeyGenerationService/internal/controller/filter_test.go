@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBlocklist_Allow(t *testing.T) {
+	b := NewBlocklist([]string{"bad", " Evil ", ""}, []string{"api", "Admin"})
+
+	cases := []struct {
+		key  string
+		want bool
+	}{
+		{"good", true},
+		{"bad", false},
+		{"xBADx", false},
+		{"b4d", false},
+		{"B4Dz", false},
+		{"3v1l", false},
+		{"evIL", false},
+		{"ev1L", false},
+		{"api", false},
+		{"API", false},
+		{"admin", false},
+		{"apis", true},
+		{"4pi", true},
+		{"bd", true},
+		{"", true},
+	}
+	for _, c := range cases {
+		if have := b.Allow(c.key); have != c.want {
+			t.Errorf("Error incorrect verdict for %v: Have %v, want %v.\n", c.key, have, c.want)
+		}
+	}
+}
+
+func TestLoadWordList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# offensive words\nbad\n\n  evil  \n#not this\n"), 0o600); err != nil {
+		t.Fatalf("Error writing word list: %v.\n", err)
+	}
+
+	words, err := LoadWordList(path)
+	if err != nil {
+		t.Fatalf("Error loading word list: %v.\n", err)
+	}
+	if want := []string{"bad", "evil"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("Error incorrect words: Have %v, want %v.\n", words, want)
+	}
+
+	if _, err := LoadWordList(filepath.Join(t.TempDir(), "missing.txt")); !errors.Is(err, ErrInvalidWordList) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidWordList)
+	}
+}
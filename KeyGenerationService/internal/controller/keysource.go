@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"crypto/rand"
+)
+
+// KeySource is the interface that wraps generating a single key of a given length from LetterBytes.
+type KeySource interface {
+	Key(length int) (string, error)
+}
+
+// FastKeySource generates keys with math/rand.
+// It is cheap, but the generated keys are predictable to anyone who has seen enough of them.
+type FastKeySource struct{}
+
+// Key generates a key of the given length with math/rand.
+func (FastKeySource) Key(length int) (string, error) {
+	return generateKey(length)
+}
+
+// SecureKeySource generates keys with crypto/rand, making them infeasible to predict.
+type SecureKeySource struct{}
+
+// Key generates a key of the given length with crypto/rand.
+func (SecureKeySource) Key(length int) (string, error) {
+	return generateSecureKey(length)
+}
+
+// maxUnbiasedByte is the largest multiple of len(LetterBytes) that fits in a byte.
+// Random bytes at or above it are rejected, otherwise 'byte % len(LetterBytes)' favours the first characters.
+const maxUnbiasedByte = 256 - 256%len(LetterBytes)
+
+// generateSecureKey generates a key by sampling LetterBytes uniformly with crypto/rand.
+func generateSecureKey(length int) (string, error) {
+	if length <= 0 {
+		return "", ErrInvalidKeyLength
+	}
+	res := make([]byte, 0, length)
+
+	// Most bytes are accepted, so reading a little more than length usually takes a single read.
+	buf := make([]byte, length+length/4+1)
+	for len(res) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= maxUnbiasedByte {
+				continue
+			}
+			res = append(res, LetterBytes[int(b)%len(LetterBytes)])
+			if len(res) == length {
+				break
+			}
+		}
+	}
+	return string(res), nil
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeySource_Key(t *testing.T) {
+	sources := map[string]KeySource{
+		"fast":   FastKeySource{},
+		"secure": SecureKeySource{},
+	}
+
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			lengthCases := []int{-1, 0, 1, 4, 64}
+
+			for _, length := range lengthCases {
+				key, err := source.Key(length)
+				if err != nil {
+					if length <= 0 && !errors.Is(err, ErrInvalidKeyLength) {
+						t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidKeyLength)
+					}
+					if length > 0 {
+						t.Errorf("Error generating key: %v.\n", err)
+					}
+					continue
+				}
+
+				if len(key) != length {
+					t.Errorf("Error incorrect generated key length: Have %v, want %v.\n", len(key), length)
+				}
+				for _, c := range key {
+					if !strings.ContainsRune(LetterBytes, c) {
+						t.Errorf("Error generated key contains character %q outside LetterBytes.\n", c)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_generateSecureKey_Uniform(t *testing.T) {
+	samplesPerLetter := 2000
+	key, err := generateSecureKey(len(LetterBytes) * samplesPerLetter)
+	if err != nil {
+		t.Fatalf("Error generating key: %v.\n", err)
+	}
+
+	counts := make(map[rune]int)
+	for _, c := range key {
+		counts[c]++
+	}
+
+	// Every letter should be drawn, and none should be far off the expected amount.
+	// The bounds are loose enough to never fail by chance, but catch a modulo bias or a missing letter.
+	for _, c := range LetterBytes {
+		if counts[c] < samplesPerLetter*3/4 || counts[c] > samplesPerLetter*5/4 {
+			t.Errorf("Error letter %q is drawn %v times, want about %v.\n", c, counts[c], samplesPerLetter)
+		}
+	}
+}
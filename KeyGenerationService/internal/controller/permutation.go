@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// feistelRounds is the amount of rounds of the Feistel network, 8 rounds mix the halves thoroughly.
+const feistelRounds = 8
+
+var (
+	ErrKeySpaceTooLarge  = errors.New("error key space of the given key length doesn't fit in 64 bits")
+	ErrKeySpaceExhausted = errors.New("error every key of the given key length has been generated")
+	ErrIndexOOR          = errors.New("error index out of key space range")
+	ErrEmptySecret       = errors.New("error cannot have an empty permutation secret")
+)
+
+// Permutation walks the key space of a given length in an order that is deterministic for a secret,
+// but unpredictable without it.
+// Index i of the key space maps to a unique key, so enumerating indexes 0, 1, 2, ... never produces the same key twice.
+// The secret must stay the same for the lifetime of the key pool, otherwise generated keys can collide.
+type Permutation struct {
+	block cipher.Block
+}
+
+// NewPermutation creates a Permutation keyed by secret.
+func NewPermutation(secret []byte) (*Permutation, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+
+	// Derive a fixed size AES key, the block cipher is used as the Feistel round function.
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Permutation{block: block}, nil
+}
+
+// Capacity returns the amount of keys of the given length, len(LetterBytes) ^ length.
+func Capacity(length int) (uint64, error) {
+	if length <= 0 {
+		return 0, ErrInvalidKeyLength
+	}
+
+	capacity := uint64(1)
+	for i := 0; i < length; i++ {
+		hi, lo := bits.Mul64(capacity, uint64(len(LetterBytes)))
+		if hi != 0 {
+			return 0, ErrKeySpaceTooLarge
+		}
+		capacity = lo
+	}
+	return capacity, nil
+}
+
+// KeyAt returns the key at index of the permuted key space of the given length.
+func (p *Permutation) KeyAt(length int, index uint64) (string, error) {
+	capacity, err := Capacity(length)
+	if err != nil {
+		return "", err
+	}
+	if index >= capacity {
+		return "", ErrIndexOOR
+	}
+
+	// The Feistel network permutes a domain of 2 ^ (2 * halfBits) values, which is at least capacity.
+	// Cycle walking re-applies it until the result lands inside the key space, keeping it a bijection on [0, capacity).
+	halfBits := (bits.Len64(capacity-1) + 1) / 2
+	value := p.feistel(length, halfBits, index)
+	for value >= capacity {
+		value = p.feistel(length, halfBits, value)
+	}
+
+	// Encode the permuted index in base len(LetterBytes).
+	res := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		res[i] = LetterBytes[value%uint64(len(LetterBytes))]
+		value /= uint64(len(LetterBytes))
+	}
+	return string(res), nil
+}
+
+// feistel runs a balanced Feistel network over a value of 2 * halfBits bits.
+func (p *Permutation) feistel(length, halfBits int, value uint64) uint64 {
+	mask := uint64(1)<<halfBits - 1
+	left, right := value>>halfBits&mask, value&mask
+
+	var in, out [aes.BlockSize]byte
+	for round := 0; round < feistelRounds; round++ {
+		// The round function encrypts the round, key length and right half, so every length gets its own permutation.
+		in[0], in[1] = byte(round), byte(length)
+		binary.BigEndian.PutUint64(in[2:10], right)
+		p.block.Encrypt(out[:], in[:])
+
+		left, right = right, left^(binary.BigEndian.Uint64(out[:8])&mask)
+	}
+	return left<<halfBits | right
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCapacity(t *testing.T) {
+	cases := map[int]uint64{1: 62, 4: 14776336, 10: 839299365868340224}
+
+	for length, want := range cases {
+		capacity, err := Capacity(length)
+		if err != nil {
+			t.Errorf("Error computing capacity: %v.\n", err)
+		}
+		if capacity != want {
+			t.Errorf("Error incorrect capacity: Have %v, want %v.\n", capacity, want)
+		}
+	}
+
+	if _, err := Capacity(0); !errors.Is(err, ErrInvalidKeyLength) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidKeyLength)
+	}
+	if _, err := Capacity(11); !errors.Is(err, ErrKeySpaceTooLarge) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrKeySpaceTooLarge)
+	}
+}
+
+func TestNewPermutation(t *testing.T) {
+	if _, err := NewPermutation(nil); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrEmptySecret)
+	}
+
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil || p == nil {
+		t.Errorf("Error creating permutation: %v.\n", err)
+	}
+}
+
+func TestPermutation_KeyAt(t *testing.T) {
+	p, err := NewPermutation([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Error creating permutation: %v.\n", err)
+	}
+
+	// Every index maps to a distinct key within LetterBytes.
+	for _, length := range []int{1, 2, 3} {
+		capacity, _ := Capacity(length)
+		seen := make(map[string]struct{}, capacity)
+
+		for index := uint64(0); index < capacity; index++ {
+			key, err := p.KeyAt(length, index)
+			if err != nil {
+				t.Fatalf("Error getting key at %v: %v.\n", index, err)
+			}
+			if len(key) != length {
+				t.Fatalf("Error incorrect key length: Have %v, want %v.\n", len(key), length)
+			}
+			for _, c := range key {
+				if !strings.ContainsRune(LetterBytes, c) {
+					t.Fatalf("Error key contains character %q outside LetterBytes.\n", c)
+				}
+			}
+			if _, ok := seen[key]; ok {
+				t.Fatalf("Error key %v is generated more than once.\n", key)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	if _, err := p.KeyAt(1, 62); !errors.Is(err, ErrIndexOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrIndexOOR)
+	}
+
+	// The order depends on the secret.
+	other, _ := NewPermutation([]byte("other secret"))
+	same := 0
+	for index := uint64(0); index < 100; index++ {
+		a, _ := p.KeyAt(4, index)
+		b, _ := other.KeyAt(4, index)
+		again, _ := p.KeyAt(4, index)
+		if a != again {
+			t.Errorf("Error permutation isn't deterministic: Have %v, want %v.\n", again, a)
+		}
+		if a == b {
+			same++
+		}
+	}
+	if same > 1 {
+		t.Errorf("Error permutations of different secrets share %v of 100 keys.\n", same)
+	}
+}
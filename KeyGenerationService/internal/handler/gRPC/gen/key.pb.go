@@ -122,6 +122,693 @@ func (x *GetKeyMetadataResponse) GetKeys() []string {
 	return nil
 }
 
+// StreamKeysRequest subscribes to or acknowledges keys on a StreamKeys stream.
+// The first request must set BufferSize, later requests report how many keys were Consumed since the last one.
+// BufferSize may be changed on any request.
+type StreamKeysRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BufferSize int64 `protobuf:"varint,1,opt,name=BufferSize,proto3" json:"BufferSize,omitempty"`
+	Consumed   int64 `protobuf:"varint,2,opt,name=Consumed,proto3" json:"Consumed,omitempty"`
+}
+
+func (x *StreamKeysRequest) Reset() {
+	*x = StreamKeysRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamKeysRequest) ProtoMessage() {}
+
+func (x *StreamKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamKeysRequest.ProtoReflect.Descriptor instead.
+func (*StreamKeysRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamKeysRequest) GetBufferSize() int64 {
+	if x != nil {
+		return x.BufferSize
+	}
+	return 0
+}
+
+func (x *StreamKeysRequest) GetConsumed() int64 {
+	if x != nil {
+		return x.Consumed
+	}
+	return 0
+}
+
+// StreamKeysResponse carries a batch of fresh keys that tops the client buffer back up to BufferSize.
+type StreamKeysResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Keys []string `protobuf:"bytes,1,rep,name=Keys,proto3" json:"Keys,omitempty"`
+}
+
+func (x *StreamKeysResponse) Reset() {
+	*x = StreamKeysResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamKeysResponse) ProtoMessage() {}
+
+func (x *StreamKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamKeysResponse.ProtoReflect.Descriptor instead.
+func (*StreamKeysResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamKeysResponse) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+// LeaseKeysRequest asks for RequiredKeys keys that return to the pool unless confirmed within TTLSeconds.
+type LeaseKeysRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RequiredKeys int64 `protobuf:"varint,1,opt,name=RequiredKeys,proto3" json:"RequiredKeys,omitempty"`
+	TTLSeconds   int64 `protobuf:"varint,2,opt,name=TTLSeconds,proto3" json:"TTLSeconds,omitempty"`
+}
+
+func (x *LeaseKeysRequest) Reset() {
+	*x = LeaseKeysRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LeaseKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseKeysRequest) ProtoMessage() {}
+
+func (x *LeaseKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseKeysRequest.ProtoReflect.Descriptor instead.
+func (*LeaseKeysRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LeaseKeysRequest) GetRequiredKeys() int64 {
+	if x != nil {
+		return x.RequiredKeys
+	}
+	return 0
+}
+
+func (x *LeaseKeysRequest) GetTTLSeconds() int64 {
+	if x != nil {
+		return x.TTLSeconds
+	}
+	return 0
+}
+
+type LeaseKeysResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool     `protobuf:"varint,1,opt,name=Success,proto3" json:"Success,omitempty"`
+	LeaseID string   `protobuf:"bytes,2,opt,name=LeaseID,proto3" json:"LeaseID,omitempty"`
+	Keys    []string `protobuf:"bytes,3,rep,name=Keys,proto3" json:"Keys,omitempty"`
+	// ExpiresAt is the lease expiry as Unix seconds.
+	ExpiresAt int64 `protobuf:"varint,4,opt,name=ExpiresAt,proto3" json:"ExpiresAt,omitempty"`
+}
+
+func (x *LeaseKeysResponse) Reset() {
+	*x = LeaseKeysResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LeaseKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseKeysResponse) ProtoMessage() {}
+
+func (x *LeaseKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseKeysResponse.ProtoReflect.Descriptor instead.
+func (*LeaseKeysResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LeaseKeysResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *LeaseKeysResponse) GetLeaseID() string {
+	if x != nil {
+		return x.LeaseID
+	}
+	return ""
+}
+
+func (x *LeaseKeysResponse) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *LeaseKeysResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+// ConfirmKeysRequest marks the given leased Keys as used.
+type ConfirmKeysRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LeaseID string   `protobuf:"bytes,1,opt,name=LeaseID,proto3" json:"LeaseID,omitempty"`
+	Keys    []string `protobuf:"bytes,2,rep,name=Keys,proto3" json:"Keys,omitempty"`
+}
+
+func (x *ConfirmKeysRequest) Reset() {
+	*x = ConfirmKeysRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConfirmKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmKeysRequest) ProtoMessage() {}
+
+func (x *ConfirmKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmKeysRequest.ProtoReflect.Descriptor instead.
+func (*ConfirmKeysRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ConfirmKeysRequest) GetLeaseID() string {
+	if x != nil {
+		return x.LeaseID
+	}
+	return ""
+}
+
+func (x *ConfirmKeysRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type ConfirmKeysResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool `protobuf:"varint,1,opt,name=Success,proto3" json:"Success,omitempty"`
+}
+
+func (x *ConfirmKeysResponse) Reset() {
+	*x = ConfirmKeysResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConfirmKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmKeysResponse) ProtoMessage() {}
+
+func (x *ConfirmKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmKeysResponse.ProtoReflect.Descriptor instead.
+func (*ConfirmKeysResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ConfirmKeysResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// ReleaseKeysRequest returns the given leased Keys to the pool, or every key left in the lease if Keys is empty.
+type ReleaseKeysRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	LeaseID string   `protobuf:"bytes,1,opt,name=LeaseID,proto3" json:"LeaseID,omitempty"`
+	Keys    []string `protobuf:"bytes,2,rep,name=Keys,proto3" json:"Keys,omitempty"`
+}
+
+func (x *ReleaseKeysRequest) Reset() {
+	*x = ReleaseKeysRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseKeysRequest) ProtoMessage() {}
+
+func (x *ReleaseKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseKeysRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseKeysRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReleaseKeysRequest) GetLeaseID() string {
+	if x != nil {
+		return x.LeaseID
+	}
+	return ""
+}
+
+func (x *ReleaseKeysRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type ReleaseKeysResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool `protobuf:"varint,1,opt,name=Success,proto3" json:"Success,omitempty"`
+}
+
+func (x *ReleaseKeysResponse) Reset() {
+	*x = ReleaseKeysResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseKeysResponse) ProtoMessage() {}
+
+func (x *ReleaseKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseKeysResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseKeysResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReleaseKeysResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// ReserveKeyRequest hands out Alias as a key chosen by the client, if it was never handed out before.
+type ReserveKeyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Alias string `protobuf:"bytes,1,opt,name=Alias,proto3" json:"Alias,omitempty"`
+}
+
+func (x *ReserveKeyRequest) Reset() {
+	*x = ReserveKeyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReserveKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveKeyRequest) ProtoMessage() {}
+
+func (x *ReserveKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveKeyRequest.ProtoReflect.Descriptor instead.
+func (*ReserveKeyRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReserveKeyRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+type ReserveKeyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool   `protobuf:"varint,1,opt,name=Success,proto3" json:"Success,omitempty"`
+	Key     string `protobuf:"bytes,2,opt,name=Key,proto3" json:"Key,omitempty"`
+}
+
+func (x *ReserveKeyResponse) Reset() {
+	*x = ReserveKeyResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReserveKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReserveKeyResponse) ProtoMessage() {}
+
+func (x *ReserveKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReserveKeyResponse.ProtoReflect.Descriptor instead.
+func (*ReserveKeyResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ReserveKeyResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReserveKeyResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type GetPoolStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetPoolStatsRequest) Reset() {
+	*x = GetPoolStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPoolStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPoolStatsRequest) ProtoMessage() {}
+
+func (x *GetPoolStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPoolStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetPoolStatsRequest) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{12}
+}
+
+// GetPoolStatsResponse reports the key pool and the key space of the current KeyLength.
+type GetPoolStatsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UnusedKeys    int64  `protobuf:"varint,1,opt,name=UnusedKeys,proto3" json:"UnusedKeys,omitempty"`
+	LeasedKeys    int64  `protobuf:"varint,2,opt,name=LeasedKeys,proto3" json:"LeasedKeys,omitempty"`
+	UsedKeys      int64  `protobuf:"varint,3,opt,name=UsedKeys,proto3" json:"UsedKeys,omitempty"`
+	KeyLength     int64  `protobuf:"varint,4,opt,name=KeyLength,proto3" json:"KeyLength,omitempty"`
+	Capacity      uint64 `protobuf:"varint,5,opt,name=Capacity,proto3" json:"Capacity,omitempty"`
+	RemainingKeys uint64 `protobuf:"varint,6,opt,name=RemainingKeys,proto3" json:"RemainingKeys,omitempty"`
+	// ConsumptionRate is the average amount of keys handed out per second recently.
+	ConsumptionRate float64 `protobuf:"fixed64,7,opt,name=ConsumptionRate,proto3" json:"ConsumptionRate,omitempty"`
+	// SecondsToExhaustion projects when the key space runs out, it is -1 without recent consumption.
+	SecondsToExhaustion int64 `protobuf:"varint,8,opt,name=SecondsToExhaustion,proto3" json:"SecondsToExhaustion,omitempty"`
+}
+
+func (x *GetPoolStatsResponse) Reset() {
+	*x = GetPoolStatsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_key_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetPoolStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPoolStatsResponse) ProtoMessage() {}
+
+func (x *GetPoolStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_key_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPoolStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetPoolStatsResponse) Descriptor() ([]byte, []int) {
+	return file_key_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetPoolStatsResponse) GetUnusedKeys() int64 {
+	if x != nil {
+		return x.UnusedKeys
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetLeasedKeys() int64 {
+	if x != nil {
+		return x.LeasedKeys
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetUsedKeys() int64 {
+	if x != nil {
+		return x.UsedKeys
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetKeyLength() int64 {
+	if x != nil {
+		return x.KeyLength
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetCapacity() uint64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetRemainingKeys() uint64 {
+	if x != nil {
+		return x.RemainingKeys
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetConsumptionRate() float64 {
+	if x != nil {
+		return x.ConsumptionRate
+	}
+	return 0
+}
+
+func (x *GetPoolStatsResponse) GetSecondsToExhaustion() int64 {
+	if x != nil {
+		return x.SecondsToExhaustion
+	}
+	return 0
+}
+
 var File_key_proto protoreflect.FileDescriptor
 
 var file_key_proto_rawDesc = []byte{
@@ -134,13 +821,97 @@ var file_key_proto_rawDesc = []byte{
 	0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a, 0x04,
 	0x4b, 0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x4b, 0x65, 0x79, 0x73,
-	0x32, 0x59, 0x0a, 0x14, 0x4b, 0x65, 0x79, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x41, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4b,
-	0x65, 0x79, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x2e, 0x47, 0x65, 0x74,
-	0x4b, 0x65, 0x79, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x17, 0x2e, 0x47, 0x65, 0x74, 0x4b, 0x65, 0x79, 0x4d, 0x65, 0x74, 0x61, 0x64,
-	0x61, 0x74, 0x61, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x06, 0x5a, 0x04, 0x2f,
-	0x67, 0x65, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x22, 0x4f, 0x0a, 0x11, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x42, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53,
+	0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x42, 0x75, 0x66, 0x66, 0x65,
+	0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x65,
+	0x64, 0x22, 0x28, 0x0a, 0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x22, 0x56, 0x0a, 0x10, 0x4c,
+	0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x22, 0x0a, 0x0c, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x4b, 0x65, 0x79, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x4b,
+	0x65, 0x79, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x54, 0x54, 0x4c, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x54, 0x54, 0x4c, 0x53, 0x65, 0x63, 0x6f,
+	0x6e, 0x64, 0x73, 0x22, 0x79, 0x0a, 0x11, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65,
+	0x73, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x44, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04,
+	0x4b, 0x65, 0x79, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x4b, 0x65, 0x79, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x22, 0x42,
+	0x0a, 0x12, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x44, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x44, 0x12, 0x12,
+	0x0a, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x4b, 0x65,
+	0x79, 0x73, 0x22, 0x2f, 0x0a, 0x13, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x4b, 0x65, 0x79,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x22, 0x42, 0x0a, 0x12, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65,
+	0x79, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x4c, 0x65, 0x61,
+	0x73, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x4c, 0x65, 0x61, 0x73,
+	0x65, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x22, 0x2f, 0x0a, 0x13, 0x52, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x07, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x29, 0x0a, 0x11, 0x52, 0x65, 0x73, 0x65,
+	0x72, 0x76, 0x65, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x41, 0x6c, 0x69, 0x61, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x41, 0x6c,
+	0x69, 0x61, 0x73, 0x22, 0x40, 0x0a, 0x12, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x4b, 0x65,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x53, 0x75, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x53, 0x75, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x4b, 0x65, 0x79, 0x22, 0x15, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xae, 0x02, 0x0a,
+	0x14, 0x47, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x55, 0x6e, 0x75, 0x73, 0x65, 0x64, 0x4b,
+	0x65, 0x79, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x55, 0x6e, 0x75, 0x73, 0x65,
+	0x64, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x4b,
+	0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x4c, 0x65, 0x61, 0x73, 0x65,
+	0x64, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x55, 0x73, 0x65, 0x64, 0x4b, 0x65, 0x79,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x55, 0x73, 0x65, 0x64, 0x4b, 0x65, 0x79,
+	0x73, 0x12, 0x1c, 0x0a, 0x09, 0x4b, 0x65, 0x79, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x4b, 0x65, 0x79, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12,
+	0x1a, 0x0a, 0x08, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x08, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x24, 0x0a, 0x0d, 0x52,
+	0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x4b, 0x65, 0x79, 0x73, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x0d, 0x52, 0x65, 0x6d, 0x61, 0x69, 0x6e, 0x69, 0x6e, 0x67, 0x4b, 0x65, 0x79,
+	0x73, 0x12, 0x28, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x73, 0x75, 0x6d, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x61, 0x74, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x43, 0x6f, 0x6e, 0x73,
+	0x75, 0x6d, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x61, 0x74, 0x65, 0x12, 0x30, 0x0a, 0x13, 0x53,
+	0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x54, 0x6f, 0x45, 0x78, 0x68, 0x61, 0x75, 0x73, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x13, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x54, 0x6f, 0x45, 0x78, 0x68, 0x61, 0x75, 0x73, 0x74, 0x69, 0x6f, 0x6e, 0x32, 0xb0, 0x03,
+	0x0a, 0x14, 0x4b, 0x65, 0x79, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x41, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4b, 0x65, 0x79,
+	0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x2e, 0x47, 0x65, 0x74, 0x4b, 0x65,
+	0x79, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x17, 0x2e, 0x47, 0x65, 0x74, 0x4b, 0x65, 0x79, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39, 0x0a, 0x0a, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x12, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x28, 0x01, 0x30, 0x01, 0x12, 0x32, 0x0a, 0x09, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79,
+	0x73, 0x12, 0x11, 0x2e, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x38, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x72, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x13, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72,
+	0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x38, 0x0a, 0x0b, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79,
+	0x73, 0x12, 0x13, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x4b, 0x65, 0x79, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
+	0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x35, 0x0a, 0x0a,
+	0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x12, 0x2e, 0x52, 0x65, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13,
+	0x2e, 0x52, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x4b, 0x65, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x53, 0x74,
+	0x61, 0x74, 0x73, 0x12, 0x14, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x47, 0x65, 0x74, 0x50,
+	0x6f, 0x6f, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x42, 0x06, 0x5a, 0x04, 0x2f, 0x67, 0x65, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -155,19 +926,43 @@ func file_key_proto_rawDescGZIP() []byte {
 	return file_key_proto_rawDescData
 }
 
-var file_key_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_key_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_key_proto_goTypes = []interface{}{
 	(*GetKeyMetadataRequest)(nil),  // 0: GetKeyMetadataRequest
 	(*GetKeyMetadataResponse)(nil), // 1: GetKeyMetadataResponse
+	(*StreamKeysRequest)(nil),      // 2: StreamKeysRequest
+	(*StreamKeysResponse)(nil),     // 3: StreamKeysResponse
+	(*LeaseKeysRequest)(nil),       // 4: LeaseKeysRequest
+	(*LeaseKeysResponse)(nil),      // 5: LeaseKeysResponse
+	(*ConfirmKeysRequest)(nil),     // 6: ConfirmKeysRequest
+	(*ConfirmKeysResponse)(nil),    // 7: ConfirmKeysResponse
+	(*ReleaseKeysRequest)(nil),     // 8: ReleaseKeysRequest
+	(*ReleaseKeysResponse)(nil),    // 9: ReleaseKeysResponse
+	(*ReserveKeyRequest)(nil),      // 10: ReserveKeyRequest
+	(*ReserveKeyResponse)(nil),     // 11: ReserveKeyResponse
+	(*GetPoolStatsRequest)(nil),    // 12: GetPoolStatsRequest
+	(*GetPoolStatsResponse)(nil),   // 13: GetPoolStatsResponse
 }
 var file_key_proto_depIdxs = []int32{
-	0, // 0: KeyGenerationService.GetKeyMetadata:input_type -> GetKeyMetadataRequest
-	1, // 1: KeyGenerationService.GetKeyMetadata:output_type -> GetKeyMetadataResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: KeyGenerationService.GetKeyMetadata:input_type -> GetKeyMetadataRequest
+	2,  // 1: KeyGenerationService.StreamKeys:input_type -> StreamKeysRequest
+	4,  // 2: KeyGenerationService.LeaseKeys:input_type -> LeaseKeysRequest
+	6,  // 3: KeyGenerationService.ConfirmKeys:input_type -> ConfirmKeysRequest
+	8,  // 4: KeyGenerationService.ReleaseKeys:input_type -> ReleaseKeysRequest
+	10, // 5: KeyGenerationService.ReserveKey:input_type -> ReserveKeyRequest
+	12, // 6: KeyGenerationService.GetPoolStats:input_type -> GetPoolStatsRequest
+	1,  // 7: KeyGenerationService.GetKeyMetadata:output_type -> GetKeyMetadataResponse
+	3,  // 8: KeyGenerationService.StreamKeys:output_type -> StreamKeysResponse
+	5,  // 9: KeyGenerationService.LeaseKeys:output_type -> LeaseKeysResponse
+	7,  // 10: KeyGenerationService.ConfirmKeys:output_type -> ConfirmKeysResponse
+	9,  // 11: KeyGenerationService.ReleaseKeys:output_type -> ReleaseKeysResponse
+	11, // 12: KeyGenerationService.ReserveKey:output_type -> ReserveKeyResponse
+	13, // 13: KeyGenerationService.GetPoolStats:output_type -> GetPoolStatsResponse
+	7,  // [7:14] is the sub-list for method output_type
+	0,  // [0:7] is the sub-list for method input_type
+	0,  // [0:0] is the sub-list for extension type_name
+	0,  // [0:0] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
 }
 
 func init() { file_key_proto_init() }
@@ -200,6 +995,150 @@ func file_key_proto_init() {
 				return nil
 			}
 		}
+		file_key_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamKeysRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamKeysResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LeaseKeysRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LeaseKeysResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConfirmKeysRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConfirmKeysResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseKeysRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseKeysResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReserveKeyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReserveKeyResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPoolStatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_key_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetPoolStatsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -207,7 +1146,7 @@ func file_key_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_key_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,12 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	KeyGenerationService_GetKeyMetadata_FullMethodName = "/KeyGenerationService/GetKeyMetadata"
+	KeyGenerationService_StreamKeys_FullMethodName     = "/KeyGenerationService/StreamKeys"
+	KeyGenerationService_LeaseKeys_FullMethodName      = "/KeyGenerationService/LeaseKeys"
+	KeyGenerationService_ConfirmKeys_FullMethodName    = "/KeyGenerationService/ConfirmKeys"
+	KeyGenerationService_ReleaseKeys_FullMethodName    = "/KeyGenerationService/ReleaseKeys"
+	KeyGenerationService_ReserveKey_FullMethodName     = "/KeyGenerationService/ReserveKey"
+	KeyGenerationService_GetPoolStats_FullMethodName   = "/KeyGenerationService/GetPoolStats"
 )
 
 // KeyGenerationServiceClient is the client API for KeyGenerationService service.
@@ -27,6 +33,12 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type KeyGenerationServiceClient interface {
 	GetKeyMetadata(ctx context.Context, in *GetKeyMetadataRequest, opts ...grpc.CallOption) (*GetKeyMetadataResponse, error)
+	StreamKeys(ctx context.Context, opts ...grpc.CallOption) (KeyGenerationService_StreamKeysClient, error)
+	LeaseKeys(ctx context.Context, in *LeaseKeysRequest, opts ...grpc.CallOption) (*LeaseKeysResponse, error)
+	ConfirmKeys(ctx context.Context, in *ConfirmKeysRequest, opts ...grpc.CallOption) (*ConfirmKeysResponse, error)
+	ReleaseKeys(ctx context.Context, in *ReleaseKeysRequest, opts ...grpc.CallOption) (*ReleaseKeysResponse, error)
+	ReserveKey(ctx context.Context, in *ReserveKeyRequest, opts ...grpc.CallOption) (*ReserveKeyResponse, error)
+	GetPoolStats(ctx context.Context, in *GetPoolStatsRequest, opts ...grpc.CallOption) (*GetPoolStatsResponse, error)
 }
 
 type keyGenerationServiceClient struct {
@@ -46,11 +58,93 @@ func (c *keyGenerationServiceClient) GetKeyMetadata(ctx context.Context, in *Get
 	return out, nil
 }
 
+func (c *keyGenerationServiceClient) StreamKeys(ctx context.Context, opts ...grpc.CallOption) (KeyGenerationService_StreamKeysClient, error) {
+	stream, err := c.cc.NewStream(ctx, &KeyGenerationService_ServiceDesc.Streams[0], KeyGenerationService_StreamKeys_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keyGenerationServiceStreamKeysClient{stream}
+	return x, nil
+}
+
+type KeyGenerationService_StreamKeysClient interface {
+	Send(*StreamKeysRequest) error
+	Recv() (*StreamKeysResponse, error)
+	grpc.ClientStream
+}
+
+type keyGenerationServiceStreamKeysClient struct {
+	grpc.ClientStream
+}
+
+func (x *keyGenerationServiceStreamKeysClient) Send(m *StreamKeysRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *keyGenerationServiceStreamKeysClient) Recv() (*StreamKeysResponse, error) {
+	m := new(StreamKeysResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keyGenerationServiceClient) LeaseKeys(ctx context.Context, in *LeaseKeysRequest, opts ...grpc.CallOption) (*LeaseKeysResponse, error) {
+	out := new(LeaseKeysResponse)
+	err := c.cc.Invoke(ctx, KeyGenerationService_LeaseKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyGenerationServiceClient) ConfirmKeys(ctx context.Context, in *ConfirmKeysRequest, opts ...grpc.CallOption) (*ConfirmKeysResponse, error) {
+	out := new(ConfirmKeysResponse)
+	err := c.cc.Invoke(ctx, KeyGenerationService_ConfirmKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyGenerationServiceClient) ReleaseKeys(ctx context.Context, in *ReleaseKeysRequest, opts ...grpc.CallOption) (*ReleaseKeysResponse, error) {
+	out := new(ReleaseKeysResponse)
+	err := c.cc.Invoke(ctx, KeyGenerationService_ReleaseKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyGenerationServiceClient) ReserveKey(ctx context.Context, in *ReserveKeyRequest, opts ...grpc.CallOption) (*ReserveKeyResponse, error) {
+	out := new(ReserveKeyResponse)
+	err := c.cc.Invoke(ctx, KeyGenerationService_ReserveKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyGenerationServiceClient) GetPoolStats(ctx context.Context, in *GetPoolStatsRequest, opts ...grpc.CallOption) (*GetPoolStatsResponse, error) {
+	out := new(GetPoolStatsResponse)
+	err := c.cc.Invoke(ctx, KeyGenerationService_GetPoolStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KeyGenerationServiceServer is the server API for KeyGenerationService service.
 // All implementations must embed UnimplementedKeyGenerationServiceServer
 // for forward compatibility
 type KeyGenerationServiceServer interface {
 	GetKeyMetadata(context.Context, *GetKeyMetadataRequest) (*GetKeyMetadataResponse, error)
+	StreamKeys(KeyGenerationService_StreamKeysServer) error
+	LeaseKeys(context.Context, *LeaseKeysRequest) (*LeaseKeysResponse, error)
+	ConfirmKeys(context.Context, *ConfirmKeysRequest) (*ConfirmKeysResponse, error)
+	ReleaseKeys(context.Context, *ReleaseKeysRequest) (*ReleaseKeysResponse, error)
+	ReserveKey(context.Context, *ReserveKeyRequest) (*ReserveKeyResponse, error)
+	GetPoolStats(context.Context, *GetPoolStatsRequest) (*GetPoolStatsResponse, error)
 	mustEmbedUnimplementedKeyGenerationServiceServer()
 }
 
@@ -61,6 +155,24 @@ type UnimplementedKeyGenerationServiceServer struct {
 func (UnimplementedKeyGenerationServiceServer) GetKeyMetadata(context.Context, *GetKeyMetadataRequest) (*GetKeyMetadataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetKeyMetadata not implemented")
 }
+func (UnimplementedKeyGenerationServiceServer) StreamKeys(KeyGenerationService_StreamKeysServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamKeys not implemented")
+}
+func (UnimplementedKeyGenerationServiceServer) LeaseKeys(context.Context, *LeaseKeysRequest) (*LeaseKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaseKeys not implemented")
+}
+func (UnimplementedKeyGenerationServiceServer) ConfirmKeys(context.Context, *ConfirmKeysRequest) (*ConfirmKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmKeys not implemented")
+}
+func (UnimplementedKeyGenerationServiceServer) ReleaseKeys(context.Context, *ReleaseKeysRequest) (*ReleaseKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseKeys not implemented")
+}
+func (UnimplementedKeyGenerationServiceServer) ReserveKey(context.Context, *ReserveKeyRequest) (*ReserveKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveKey not implemented")
+}
+func (UnimplementedKeyGenerationServiceServer) GetPoolStats(context.Context, *GetPoolStatsRequest) (*GetPoolStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPoolStats not implemented")
+}
 func (UnimplementedKeyGenerationServiceServer) mustEmbedUnimplementedKeyGenerationServiceServer() {}
 
 // UnsafeKeyGenerationServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +204,122 @@ func _KeyGenerationService_GetKeyMetadata_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyGenerationService_StreamKeys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(KeyGenerationServiceServer).StreamKeys(&keyGenerationServiceStreamKeysServer{stream})
+}
+
+type KeyGenerationService_StreamKeysServer interface {
+	Send(*StreamKeysResponse) error
+	Recv() (*StreamKeysRequest, error)
+	grpc.ServerStream
+}
+
+type keyGenerationServiceStreamKeysServer struct {
+	grpc.ServerStream
+}
+
+func (x *keyGenerationServiceStreamKeysServer) Send(m *StreamKeysResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *keyGenerationServiceStreamKeysServer) Recv() (*StreamKeysRequest, error) {
+	m := new(StreamKeysRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _KeyGenerationService_LeaseKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyGenerationServiceServer).LeaseKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyGenerationService_LeaseKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyGenerationServiceServer).LeaseKeys(ctx, req.(*LeaseKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyGenerationService_ConfirmKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyGenerationServiceServer).ConfirmKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyGenerationService_ConfirmKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyGenerationServiceServer).ConfirmKeys(ctx, req.(*ConfirmKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyGenerationService_ReleaseKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyGenerationServiceServer).ReleaseKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyGenerationService_ReleaseKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyGenerationServiceServer).ReleaseKeys(ctx, req.(*ReleaseKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyGenerationService_ReserveKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyGenerationServiceServer).ReserveKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyGenerationService_ReserveKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyGenerationServiceServer).ReserveKey(ctx, req.(*ReserveKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyGenerationService_GetPoolStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPoolStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyGenerationServiceServer).GetPoolStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyGenerationService_GetPoolStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyGenerationServiceServer).GetPoolStats(ctx, req.(*GetPoolStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KeyGenerationService_ServiceDesc is the grpc.ServiceDesc for KeyGenerationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,7 +331,34 @@ var KeyGenerationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetKeyMetadata",
 			Handler:    _KeyGenerationService_GetKeyMetadata_Handler,
 		},
+		{
+			MethodName: "LeaseKeys",
+			Handler:    _KeyGenerationService_LeaseKeys_Handler,
+		},
+		{
+			MethodName: "ConfirmKeys",
+			Handler:    _KeyGenerationService_ConfirmKeys_Handler,
+		},
+		{
+			MethodName: "ReleaseKeys",
+			Handler:    _KeyGenerationService_ReleaseKeys_Handler,
+		},
+		{
+			MethodName: "ReserveKey",
+			Handler:    _KeyGenerationService_ReserveKey_Handler,
+		},
+		{
+			MethodName: "GetPoolStats",
+			Handler:    _KeyGenerationService_GetPoolStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamKeys",
+			Handler:       _KeyGenerationService_StreamKeys_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "key.proto",
 }
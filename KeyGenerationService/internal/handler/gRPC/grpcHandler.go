@@ -3,26 +3,225 @@ package gRPC
 import (
 	"KeyGenerationService/internal/controller"
 	"KeyGenerationService/internal/handler/gRPC/gen"
+	"KeyGenerationService/internal/identity"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Handler implements the generated gRPC server and is responsible for accepting all incoming GetKeyMetadata requests.
 type Handler struct {
 	gen.UnimplementedKeyGenerationServiceServer
 	controller *controller.KGS
+
+	// maxBatchSize is the most keys a single request can ask for, zero means unbounded.
+	maxBatchSize int64
+	// quota, if set, limits how many keys each client can take over time.
+	quota *quota
+}
+
+// Option configures optional behaviour of Handler.
+type Option func(*Handler)
+
+// WithMaxBatchSize rejects requests asking for more than n keys at once.
+func WithMaxBatchSize(n int) Option {
+	return func(h *Handler) {
+		h.maxBatchSize = int64(n)
+	}
+}
+
+// WithClientQuota limits every client to keys keys per period. Clients are told apart by identity.PrincipalFromContext,
+// so the x-client-id metadata can't be used to get a fresh quota.
+func WithClientQuota(keys int, period time.Duration) Option {
+	return func(h *Handler) {
+		h.quota = newQuota(keys, period, time.Now)
+	}
 }
 
 // New creates a new handler instance.
-func New(ctrl *controller.KGS) *Handler {
-	return &Handler{controller: ctrl}
+func New(ctrl *controller.KGS, opts ...Option) *Handler {
+	h := &Handler{controller: ctrl}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // GetKeyMetadata accepts all incoming gen.GetKeyMetadataRequest and fetches keys from the database.
 func (h *Handler) GetKeyMetadata(ctx context.Context, req *gen.GetKeyMetadataRequest) (*gen.GetKeyMetadataResponse, error) {
-	keys, err := h.controller.GetKeys(int(req.RequiredKeys))
+	client, err := h.admit(ctx, req.RequiredKeys)
 	if err != nil {
-		// TODO: Handle GetKeys error
 		return &gen.GetKeyMetadataResponse{Success: false}, err
 	}
+
+	keys, err := h.controller.GetKeys(ctx, int(req.RequiredKeys))
+	if err != nil {
+		h.refund(client, req.RequiredKeys)
+		return &gen.GetKeyMetadataResponse{Success: false}, h.statusError(ctx, err)
+	}
 	return &gen.GetKeyMetadataResponse{Keys: keys, Success: true}, nil
 }
+
+// admit checks a request for requiredKeys keys against the maximum batch size and takes the keys from the quota of
+// the calling client, whose identity is returned. Counts the controller rejects anyway are let through untouched.
+func (h *Handler) admit(ctx context.Context, requiredKeys int64) (string, error) {
+	if requiredKeys <= 0 {
+		return "", nil
+	}
+	if h.maxBatchSize > 0 && requiredKeys > h.maxBatchSize {
+		return "", batchTooLargeError(requiredKeys, h.maxBatchSize)
+	}
+	if h.quota == nil {
+		return "", nil
+	}
+
+	client := identity.PrincipalFromContext(ctx)
+	if wait, ok := h.quota.take(client, int(requiredKeys)); !ok {
+		return "", quotaExceededError(client, h.quota, wait)
+	}
+	return client, nil
+}
+
+// refund gives keys taken by admit back to the quota of client, when they weren't handed out after all.
+func (h *Handler) refund(client string, requiredKeys int64) {
+	if h.quota != nil && requiredKeys > 0 {
+		h.quota.refund(client, int(requiredKeys))
+	}
+}
+
+// StreamKeys keeps a client's key buffer filled for as long as the stream is open.
+// The client announces its buffer size, and every time it acknowledges consumed keys,
+// the same amount of fresh keys is fetched from the database and sent back.
+func (h *Handler) StreamKeys(stream gen.KeyGenerationService_StreamKeysServer) error {
+	var bufferSize, outstanding int64
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.BufferSize < 0 || req.Consumed < 0 {
+			return status.Error(codes.InvalidArgument, "buffer size and consumed keys cannot be negative")
+		}
+		if req.BufferSize > 0 {
+			bufferSize = req.BufferSize
+		}
+		if bufferSize == 0 {
+			return status.Error(codes.InvalidArgument, "buffer size must be set on the first request")
+		}
+		if h.maxBatchSize > 0 && bufferSize > h.maxBatchSize {
+			return batchTooLargeError(bufferSize, h.maxBatchSize)
+		}
+
+		// A client cannot consume more keys than it was sent.
+		outstanding -= min(req.Consumed, outstanding)
+
+		required := bufferSize - outstanding
+		if required <= 0 {
+			continue
+		}
+
+		client, err := h.admit(stream.Context(), required)
+		if err != nil {
+			return err
+		}
+		keys, err := h.controller.GetKeys(stream.Context(), int(required))
+		if err != nil {
+			h.refund(client, required)
+			return h.statusError(stream.Context(), err)
+		}
+		if err := stream.Send(&gen.StreamKeysResponse{Keys: keys}); err != nil {
+			return err
+		}
+		outstanding += int64(len(keys))
+	}
+}
+
+// LeaseKeys accepts all incoming gen.LeaseKeysRequest and leases keys from the database.
+func (h *Handler) LeaseKeys(ctx context.Context, req *gen.LeaseKeysRequest) (*gen.LeaseKeysResponse, error) {
+	// Longer TTLs would overflow time.Duration, the controller rejects them anyway.
+	if req.TTLSeconds > int64(controller.MaxLeaseTTL/time.Second) {
+		err := &controller.KGSError{Err: fmt.Errorf("%s: %w", "Lease keys error", controller.ErrInvalidTTL)}
+		return &gen.LeaseKeysResponse{Success: false}, h.statusError(ctx, err)
+	}
+	client, err := h.admit(ctx, req.RequiredKeys)
+	if err != nil {
+		return &gen.LeaseKeysResponse{Success: false}, err
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	lease, err := h.controller.LeaseKeys(ctx, int(req.RequiredKeys), ttl)
+	if err != nil {
+		h.refund(client, req.RequiredKeys)
+		return &gen.LeaseKeysResponse{Success: false}, h.statusError(ctx, err)
+	}
+	return &gen.LeaseKeysResponse{
+		Success:   true,
+		LeaseID:   lease.ID,
+		Keys:      lease.Keys,
+		ExpiresAt: lease.ExpiresAt.Unix(),
+	}, nil
+}
+
+// ConfirmKeys accepts all incoming gen.ConfirmKeysRequest and marks the leased keys as used.
+func (h *Handler) ConfirmKeys(ctx context.Context, req *gen.ConfirmKeysRequest) (*gen.ConfirmKeysResponse, error) {
+	if err := h.controller.ConfirmKeys(ctx, req.LeaseID, req.Keys); err != nil {
+		return &gen.ConfirmKeysResponse{Success: false}, h.statusError(ctx, err)
+	}
+	return &gen.ConfirmKeysResponse{Success: true}, nil
+}
+
+// ReleaseKeys accepts all incoming gen.ReleaseKeysRequest and returns the leased keys to the pool.
+func (h *Handler) ReleaseKeys(ctx context.Context, req *gen.ReleaseKeysRequest) (*gen.ReleaseKeysResponse, error) {
+	if err := h.controller.ReleaseKeys(ctx, req.LeaseID, req.Keys); err != nil {
+		return &gen.ReleaseKeysResponse{Success: false}, h.statusError(ctx, err)
+	}
+	return &gen.ReleaseKeysResponse{Success: true}, nil
+}
+
+// ReserveKey accepts all incoming gen.ReserveKeyRequest and hands out the alias as a key. It counts towards the
+// client quota like any other key.
+func (h *Handler) ReserveKey(ctx context.Context, req *gen.ReserveKeyRequest) (*gen.ReserveKeyResponse, error) {
+	client, err := h.admit(ctx, 1)
+	if err != nil {
+		return &gen.ReserveKeyResponse{Success: false}, err
+	}
+
+	if err := h.controller.ReserveKey(ctx, req.Alias); err != nil {
+		h.refund(client, 1)
+		return &gen.ReserveKeyResponse{Success: false}, h.statusError(ctx, err)
+	}
+	return &gen.ReserveKeyResponse{Success: true, Key: req.Alias}, nil
+}
+
+// GetPoolStats accepts all incoming gen.GetPoolStatsRequest and reports the state of the key pool and key space.
+func (h *Handler) GetPoolStats(ctx context.Context, req *gen.GetPoolStatsRequest) (*gen.GetPoolStatsResponse, error) {
+	stats, err := h.controller.PoolStats(ctx)
+	if err != nil {
+		return nil, h.statusError(ctx, err)
+	}
+
+	secondsToExhaustion := int64(-1)
+	if stats.TimeToExhaustion >= 0 {
+		secondsToExhaustion = int64(stats.TimeToExhaustion / time.Second)
+	}
+	return &gen.GetPoolStatsResponse{
+		UnusedKeys:          int64(stats.UnusedKeys),
+		LeasedKeys:          int64(stats.LeasedKeys),
+		UsedKeys:            int64(stats.UsedKeys),
+		KeyLength:           int64(stats.KeyLength),
+		Capacity:            stats.Capacity,
+		RemainingKeys:       stats.RemainingKeys,
+		ConsumptionRate:     stats.ConsumptionRate,
+		SecondsToExhaustion: secondsToExhaustion,
+	}, nil
+}
@@ -0,0 +1,352 @@
+package gRPC
+
+import (
+	"KeyGenerationService/internal/auth"
+	"KeyGenerationService/internal/controller"
+	"KeyGenerationService/internal/handler/gRPC/gen"
+	"KeyGenerationService/internal/identity"
+	"KeyGenerationService/internal/repository/memory"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves a Handler backed by an in-memory database with poolSize keys over an in-process connection.
+func newTestClient(t *testing.T, poolSize int, opts ...Option) gen.KeyGenerationServiceClient {
+	t.Helper()
+	return newTestClientWithServerOptions(t, poolSize, nil, opts...)
+}
+
+// newTestClientWithServerOptions is newTestClient with srvOpts applied to the gRPC server.
+func newTestClientWithServerOptions(t *testing.T, poolSize int, srvOpts []grpc.ServerOption, opts ...Option) gen.KeyGenerationServiceClient {
+	t.Helper()
+
+	db, err := memory.New()
+	if err != nil {
+		t.Fatalf("Error creating instance DB: %v.\n", err)
+	}
+	kgs, err := controller.New(context.Background(), db, poolSize, 4)
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(srvOpts...)
+	gen.RegisterKeyGenerationServiceServer(srv, New(kgs, opts...))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing handler: %v.\n", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return gen.NewKeyGenerationServiceClient(conn)
+}
+
+func TestHandler_GetKeyMetadata(t *testing.T) {
+	client := newTestClient(t, 100)
+
+	resp, err := client.GetKeyMetadata(context.Background(), &gen.GetKeyMetadataRequest{RequiredKeys: 10})
+	if err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	if !resp.Success || len(resp.Keys) != 10 {
+		t.Errorf("Error incorrect response: Have %v keys, want %v.\n", len(resp.Keys), 10)
+	}
+}
+
+func TestHandler_StreamKeys(t *testing.T) {
+	client := newTestClient(t, 100)
+	seen := make(map[string]struct{})
+
+	recv := func(stream gen.KeyGenerationService_StreamKeysClient, want int) {
+		t.Helper()
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Error receiving keys: %v.\n", err)
+		}
+		if len(resp.Keys) != want {
+			t.Errorf("Error incorrect batch size: Have %v, want %v.\n", len(resp.Keys), want)
+		}
+		for _, key := range resp.Keys {
+			if _, ok := seen[key]; ok {
+				t.Errorf("Error key %v is streamed more than once.\n", key)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	t.Run("Test buffer refill", func(t *testing.T) {
+		stream, err := client.StreamKeys(context.Background())
+		if err != nil {
+			t.Fatalf("Error opening stream: %v.\n", err)
+		}
+
+		// 1. Subscribing fills the whole buffer.
+		_ = stream.Send(&gen.StreamKeysRequest{BufferSize: 5})
+		recv(stream, 5)
+
+		// 2. Acknowledging consumed keys tops the buffer back up.
+		_ = stream.Send(&gen.StreamKeysRequest{Consumed: 3})
+		recv(stream, 3)
+
+		// 3. Growing the buffer sends the difference.
+		_ = stream.Send(&gen.StreamKeysRequest{BufferSize: 8, Consumed: 1})
+		recv(stream, 4)
+
+		if err := stream.CloseSend(); err != nil {
+			t.Errorf("Error closing stream: %v.\n", err)
+		}
+	})
+
+	t.Run("Test missing buffer size", func(t *testing.T) {
+		stream, err := client.StreamKeys(context.Background())
+		if err != nil {
+			t.Fatalf("Error opening stream: %v.\n", err)
+		}
+
+		_ = stream.Send(&gen.StreamKeysRequest{Consumed: 1})
+		_, err = stream.Recv()
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.InvalidArgument)
+		}
+	})
+}
+
+func TestHandler_Leases(t *testing.T) {
+	client := newTestClient(t, 100)
+	ctx := context.Background()
+
+	lease, err := client.LeaseKeys(ctx, &gen.LeaseKeysRequest{RequiredKeys: 10, TTLSeconds: 60})
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	if !lease.Success || lease.LeaseID == "" || len(lease.Keys) != 10 || lease.ExpiresAt == 0 {
+		t.Errorf("Error incorrect response: %v.\n", lease)
+	}
+
+	confirm, err := client.ConfirmKeys(ctx, &gen.ConfirmKeysRequest{LeaseID: lease.LeaseID, Keys: lease.Keys[:3]})
+	if err != nil || !confirm.Success {
+		t.Errorf("Error confirming keys: %v.\n", err)
+	}
+
+	release, err := client.ReleaseKeys(ctx, &gen.ReleaseKeysRequest{LeaseID: lease.LeaseID})
+	if err != nil || !release.Success {
+		t.Errorf("Error releasing keys: %v.\n", err)
+	}
+
+	_, err = client.ReleaseKeys(ctx, &gen.ReleaseKeysRequest{LeaseID: lease.LeaseID})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestHandler_ReserveKey(t *testing.T) {
+	client := newTestClient(t, 100)
+
+	resp, err := client.ReserveKey(context.Background(), &gen.ReserveKeyRequest{Alias: "launch"})
+	if err != nil {
+		t.Fatalf("Error reserving key: %v.\n", err)
+	}
+	if !resp.Success || resp.Key != "launch" {
+		t.Errorf("Error incorrect response: Have %v, want %v.\n", resp.Key, "launch")
+	}
+}
+
+func TestHandler_GetPoolStats(t *testing.T) {
+	client := newTestClient(t, 100)
+	ctx := context.Background()
+
+	if _, err := client.GetKeyMetadata(ctx, &gen.GetKeyMetadataRequest{RequiredKeys: 10}); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+
+	stats, err := client.GetPoolStats(ctx, &gen.GetPoolStatsRequest{})
+	if err != nil {
+		t.Fatalf("Error getting pool stats: %v.\n", err)
+	}
+	if stats.UnusedKeys != 90 || stats.UsedKeys != 10 || stats.KeyLength != 4 || stats.Capacity != 14776336 {
+		t.Errorf("Error incorrect pool stats: %v.\n", stats)
+	}
+	if stats.RemainingKeys != 14776336-100 || stats.ConsumptionRate <= 0 || stats.SecondsToExhaustion <= 0 {
+		t.Errorf("Error incorrect key space stats: %v.\n", stats)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	client := newTestClient(t, 100)
+	ctx := context.Background()
+
+	lease, err := client.LeaseKeys(ctx, &gen.LeaseKeysRequest{RequiredKeys: 10, TTLSeconds: 60})
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+
+	cases := []struct {
+		name   string
+		call   func() error
+		code   codes.Code
+		reason string
+		retry  bool
+	}{
+		{"zero keys", func() error {
+			_, err := client.GetKeyMetadata(ctx, &gen.GetKeyMetadataRequest{RequiredKeys: 0})
+			return err
+		}, codes.InvalidArgument, ReasonInvalidKeyCount, false},
+		{"negative keys", func() error {
+			_, err := client.LeaseKeys(ctx, &gen.LeaseKeysRequest{RequiredKeys: -1, TTLSeconds: 60})
+			return err
+		}, codes.InvalidArgument, ReasonInvalidKeyCount, false},
+		{"invalid TTL", func() error {
+			_, err := client.LeaseKeys(ctx, &gen.LeaseKeysRequest{RequiredKeys: 1})
+			return err
+		}, codes.InvalidArgument, ReasonInvalidTTL, false},
+		{"TTL overflowing", func() error {
+			_, err := client.LeaseKeys(ctx, &gen.LeaseKeysRequest{RequiredKeys: 1, TTLSeconds: math.MaxInt64})
+			return err
+		}, codes.InvalidArgument, ReasonInvalidTTL, false},
+		{"pool exhausted", func() error {
+			_, err := client.GetKeyMetadata(ctx, &gen.GetKeyMetadataRequest{RequiredKeys: 91})
+			return err
+		}, codes.ResourceExhausted, ReasonPoolExhausted, true},
+		{"lease not found", func() error {
+			_, err := client.ReleaseKeys(ctx, &gen.ReleaseKeysRequest{LeaseID: "unknown"})
+			return err
+		}, codes.NotFound, ReasonLeaseNotFound, false},
+		{"key not leased", func() error {
+			_, err := client.ConfirmKeys(ctx, &gen.ConfirmKeysRequest{LeaseID: lease.LeaseID, Keys: []string{"none"}})
+			return err
+		}, codes.FailedPrecondition, ReasonKeyNotLeased, false},
+		{"invalid alias", func() error {
+			_, err := client.ReserveKey(ctx, &gen.ReserveKeyRequest{Alias: "launch/day"})
+			return err
+		}, codes.InvalidArgument, ReasonInvalidAlias, false},
+		{"alias used", func() error {
+			_, err := client.ReserveKey(ctx, &gen.ReserveKeyRequest{Alias: lease.Keys[0]})
+			return err
+		}, codes.AlreadyExists, ReasonKeyUsed, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := status.Convert(c.call())
+			if st.Code() != c.code {
+				t.Fatalf("Error incorrect status code: Have %v, want %v.\n", st.Code(), c.code)
+			}
+
+			var info *errdetails.ErrorInfo
+			var retry *errdetails.RetryInfo
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					info = d
+				case *errdetails.RetryInfo:
+					retry = d
+				}
+			}
+			if info == nil || info.Reason != c.reason || info.Domain != ErrorDomain {
+				t.Errorf("Error incorrect error info: Have %v, want reason %v.\n", info, c.reason)
+			}
+			if (retry != nil) != c.retry {
+				t.Errorf("Error incorrect retry info: Have %v, want %v.\n", retry, c.retry)
+			}
+			// 90 keys are left after leasing 10 of them.
+			if c.code == codes.ResourceExhausted && info != nil && info.Metadata[AvailableKeysMetadata] != "90" {
+				t.Errorf("Error incorrect available keys: Have %v, want %v.\n", info.Metadata[AvailableKeysMetadata], 90)
+			}
+		})
+	}
+}
+
+func TestHandler_Limits(t *testing.T) {
+	// Clients are told apart by their token, whatever x-client-id they name.
+	authenticator := auth.NewAuthenticator(map[string]string{"secret": "gateway", "other": "other"})
+	client := newTestClientWithServerOptions(t, 100, []grpc.ServerOption{
+		grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(authenticator.StreamServerInterceptor()),
+	}, WithMaxBatchSize(20), WithClientQuota(30, time.Hour))
+	as := func(token, id string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token, identity.MetadataKey, id)
+	}
+	reason := func(st *status.Status) string {
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok {
+				return info.Reason
+			}
+		}
+		return ""
+	}
+
+	// 1. A batch larger than the maximum is rejected.
+	_, err := client.GetKeyMetadata(as("secret", "a"), &gen.GetKeyMetadataRequest{RequiredKeys: 21})
+	if st := status.Convert(err); st.Code() != codes.InvalidArgument || reason(st) != ReasonBatchTooLarge {
+		t.Errorf("Error incorrect status: Have %v %v, want %v %v.\n", st.Code(), reason(st), codes.InvalidArgument, ReasonBatchTooLarge)
+	}
+
+	// 2. A rejected batch doesn't count towards the quota.
+	if _, err := client.GetKeyMetadata(as("secret", "a"), &gen.GetKeyMetadataRequest{RequiredKeys: 20}); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+
+	// 3. Exceeding the quota is rejected with a retry delay.
+	_, err = client.LeaseKeys(as("secret", "a"), &gen.LeaseKeysRequest{RequiredKeys: 20, TTLSeconds: 60})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted || reason(st) != ReasonQuotaExceeded {
+		t.Errorf("Error incorrect status: Have %v %v, want %v %v.\n", st.Code(), reason(st), codes.ResourceExhausted, ReasonQuotaExceeded)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = d
+		}
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() <= 0 {
+		t.Errorf("Error incorrect retry info: %v.\n", retry)
+	}
+
+	// 4. Naming other clients with x-client-id doesn't get the same identity a fresh quota.
+	for i := 0; i < 5; i++ {
+		_, err := client.GetKeyMetadata(as("secret", fmt.Sprintf("client%d", i)), &gen.GetKeyMetadataRequest{RequiredKeys: 20})
+		if st := status.Convert(err); st.Code() != codes.ResourceExhausted {
+			t.Errorf("Error incorrect status code: Have %v, want %v.\n", st.Code(), codes.ResourceExhausted)
+		}
+	}
+
+	// 5. Other identities have a quota of their own.
+	if _, err := client.GetKeyMetadata(as("other", "a"), &gen.GetKeyMetadataRequest{RequiredKeys: 20}); err != nil {
+		t.Errorf("Error getting keys: %v.\n", err)
+	}
+
+	// 6. Streams are bounded by the maximum batch size and the quota too.
+	stream, err := client.StreamKeys(as("other", "b"))
+	if err != nil {
+		t.Fatalf("Error opening stream: %v.\n", err)
+	}
+	_ = stream.Send(&gen.StreamKeysRequest{BufferSize: 20})
+	_, err = stream.Recv()
+	if st := status.Convert(err); st.Code() != codes.ResourceExhausted {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", st.Code(), codes.ResourceExhausted)
+	}
+}
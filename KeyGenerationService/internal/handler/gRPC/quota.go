@@ -0,0 +1,107 @@
+package gRPC
+
+import (
+	"sync"
+	"time"
+)
+
+// maxBuckets bounds how many clients have a bucket of their own at once.
+const maxBuckets = 10000
+
+// quota limits how many keys each client can take per period, with a token bucket per client.
+// A bucket holds at most limit keys and refills continuously at limit keys per period.
+// Once maxBuckets clients have a bucket, further clients share the overflow bucket until buckets are swept.
+type quota struct {
+	limit      int
+	period     time.Duration
+	now        func() time.Time
+	maxBuckets int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	overflow  *bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newQuota creates a quota of limit keys per period.
+func newQuota(limit int, period time.Duration, now func() time.Time) *quota {
+	return &quota{
+		limit:      limit,
+		period:     period,
+		now:        now,
+		maxBuckets: maxBuckets,
+		buckets:    make(map[string]*bucket),
+		overflow:   &bucket{tokens: float64(limit), updated: now()},
+		lastSweep:  now(),
+	}
+}
+
+// take takes n keys from the bucket of client. If the bucket holds fewer keys, nothing is taken and take returns
+// how long the client has to wait until it holds enough, or a negative duration if n exceeds the limit.
+func (q *quota) take(client string, n int) (time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.sweep(now)
+
+	b := q.refill(client, now)
+	if float64(n) <= b.tokens {
+		b.tokens -= float64(n)
+		return 0, true
+	}
+	if n > q.limit {
+		return -1, false
+	}
+	missing := float64(n) - b.tokens
+	return time.Duration(missing / float64(q.limit) * float64(q.period)), false
+}
+
+// refund returns n keys taken by client that were never handed out.
+func (q *quota) refund(client string, n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := q.refill(client, q.now())
+	b.tokens = min(b.tokens+float64(n), float64(q.limit))
+}
+
+// refill returns the bucket of client, or the overflow bucket if client has none and no more buckets fit,
+// topped up for the time passed since it was last used. mu must be held.
+func (q *quota) refill(client string, now time.Time) *bucket {
+	b, ok := q.buckets[client]
+	if !ok && len(q.buckets) < q.maxBuckets {
+		b = &bucket{tokens: float64(q.limit), updated: now}
+		q.buckets[client] = b
+		return b
+	}
+	if !ok {
+		b = q.overflow
+	}
+
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()/q.period.Seconds()*float64(q.limit), float64(q.limit))
+		b.updated = now
+	}
+	return b
+}
+
+// sweep drops, once per period, the buckets that are full again, since a new bucket starts full anyway.
+// mu must be held.
+func (q *quota) sweep(now time.Time) {
+	if now.Sub(q.lastSweep) < q.period {
+		return
+	}
+	q.lastSweep = now
+
+	for client, b := range q.buckets {
+		if now.Sub(b.updated) >= q.period {
+			delete(q.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package gRPC
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	q := newQuota(60, time.Minute, clock)
+
+	// 1. A new client can take its whole limit at once.
+	if _, ok := q.take("a", 60); !ok {
+		t.Errorf("Error taking the whole limit should succeed.\n")
+	}
+
+	// 2. Clients don't share buckets.
+	if _, ok := q.take("b", 10); !ok {
+		t.Errorf("Error taking keys of another client should succeed.\n")
+	}
+
+	// 3. An empty bucket tells how long to wait.
+	wait, ok := q.take("a", 10)
+	if ok || wait != 10*time.Second {
+		t.Errorf("Error incorrect wait: Have %v (%v), want %v.\n", wait, ok, 10*time.Second)
+	}
+
+	// 4. The bucket refills over time.
+	now = now.Add(10 * time.Second)
+	if _, ok := q.take("a", 10); !ok {
+		t.Errorf("Error taking refilled keys should succeed.\n")
+	}
+
+	// 5. Refunded keys can be taken again.
+	q.refund("a", 5)
+	if _, ok := q.take("a", 5); !ok {
+		t.Errorf("Error taking refunded keys should succeed.\n")
+	}
+
+	// 6. More than the limit can never be taken.
+	if wait, ok := q.take("c", 61); ok || wait >= 0 {
+		t.Errorf("Error incorrect wait: Have %v (%v), want a negative wait.\n", wait, ok)
+	}
+
+	// 7. Idle clients are swept.
+	now = now.Add(2 * time.Minute)
+	q.take("d", 1)
+	if len(q.buckets) != 1 {
+		t.Errorf("Error incorrect bucket count: Have %v, want %v.\n", len(q.buckets), 1)
+	}
+
+	// 8. Once all buckets are taken, new clients share the overflow bucket.
+	q.maxBuckets = 2
+	if _, ok := q.take("e", 40); !ok {
+		t.Errorf("Error taking keys of a client with a bucket of its own should succeed.\n")
+	}
+	if _, ok := q.take("f", 40); !ok {
+		t.Errorf("Error taking keys of the overflow bucket should succeed.\n")
+	}
+	if _, ok := q.take("g", 40); ok {
+		t.Errorf("Error clients of the overflow bucket shouldn't get a full bucket each.\n")
+	}
+	if len(q.buckets) != 2 {
+		t.Errorf("Error incorrect bucket count: Have %v, want %v.\n", len(q.buckets), 2)
+	}
+}
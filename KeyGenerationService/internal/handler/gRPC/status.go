@@ -0,0 +1,157 @@
+package gRPC
+
+import (
+	"KeyGenerationService/internal/controller"
+	"KeyGenerationService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the domain of every errdetails.ErrorInfo attached to a status by the Handler.
+const ErrorDomain = "keygenerationservice"
+
+// Reasons of the errdetails.ErrorInfo attached to a status, so clients can tell failures apart without parsing messages.
+const (
+	ReasonInvalidKeyCount = "INVALID_KEY_COUNT"
+	ReasonInvalidTTL      = "INVALID_TTL"
+	ReasonPoolExhausted   = "KEY_POOL_EXHAUSTED"
+	ReasonLeaseNotFound   = "LEASE_NOT_FOUND"
+	ReasonKeyNotLeased    = "KEY_NOT_LEASED"
+	ReasonTimeout         = "TIMEOUT"
+	ReasonDatabase        = "DATABASE_UNAVAILABLE"
+	ReasonBatchTooLarge   = "BATCH_TOO_LARGE"
+	ReasonQuotaExceeded   = "QUOTA_EXCEEDED"
+	ReasonInvalidAlias    = "INVALID_ALIAS"
+	ReasonKeyUsed         = "KEY_ALREADY_USED"
+)
+
+// Metadata keys of the errdetails.ErrorInfo attached to a status.
+const (
+	// AvailableKeysMetadata holds how many keys were available when the key pool couldn't serve a request.
+	AvailableKeysMetadata = "available_keys"
+	// MaxBatchSizeMetadata holds the most keys a single request can ask for.
+	MaxBatchSizeMetadata = "max_batch_size"
+	// QuotaLimitMetadata and QuotaPeriodMetadata hold the quota a client exceeded.
+	QuotaLimitMetadata  = "quota_limit"
+	QuotaPeriodMetadata = "quota_period"
+)
+
+// retryDelay is how long clients are asked to wait before retrying a request that failed for a transient reason.
+const retryDelay = time.Second
+
+// statusError converts a controller error into a gRPC status with a code and error details clients can act on.
+// Errors that already are a status, such as the ones of a broken stream, are returned as is.
+func (h *Handler) statusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	msg := strings.TrimSpace(err.Error())
+	switch {
+	case errors.Is(err, controller.ErrInvalidKeyCount):
+		return withDetails(codes.InvalidArgument, msg,
+			errorInfo(ReasonInvalidKeyCount, nil),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "RequiredKeys", Description: controller.ErrInvalidKeyCount.Error()},
+			}},
+		)
+	case errors.Is(err, controller.ErrInvalidTTL):
+		return withDetails(codes.InvalidArgument, msg,
+			errorInfo(ReasonInvalidTTL, nil),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "TTLSeconds", Description: controller.ErrInvalidTTL.Error()},
+			}},
+		)
+	case errors.Is(err, controller.ErrInvalidAlias):
+		return withDetails(codes.InvalidArgument, msg,
+			errorInfo(ReasonInvalidAlias, nil),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "Alias", Description: controller.ErrInvalidAlias.Error()},
+			}},
+		)
+	case errors.Is(err, repository.ErrKeyUsed):
+		return withDetails(codes.AlreadyExists, msg, errorInfo(ReasonKeyUsed, nil))
+	case errors.Is(err, repository.ErrKeyOOR):
+		// The pool is replenished in the background, so the request may succeed later or with fewer keys.
+		metadata := make(map[string]string)
+		if available, err := h.controller.AvailableKeys(ctx); err == nil {
+			metadata[AvailableKeysMetadata] = strconv.Itoa(available)
+		}
+		return withDetails(codes.ResourceExhausted, msg, errorInfo(ReasonPoolExhausted, metadata), retryInfo())
+	case errors.Is(err, repository.ErrLeaseNotFound):
+		return withDetails(codes.NotFound, msg, errorInfo(ReasonLeaseNotFound, nil))
+	case errors.Is(err, repository.ErrKeyNotLeased):
+		return withDetails(codes.FailedPrecondition, msg, errorInfo(ReasonKeyNotLeased, nil))
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, msg)
+	case errors.Is(err, context.DeadlineExceeded):
+		return withDetails(codes.DeadlineExceeded, msg, errorInfo(ReasonTimeout, nil), retryInfo())
+	case errors.Is(err, controller.ErrGetKeysError), errors.Is(err, controller.ErrLeaseError),
+		errors.Is(err, controller.ErrReserveError), errors.Is(err, controller.ErrRepoError):
+		return withDetails(codes.Unavailable, msg, errorInfo(ReasonDatabase, nil), retryInfo())
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+// batchTooLargeError reports a request for requiredKeys keys that exceeds the maximum batch size.
+func batchTooLargeError(requiredKeys, maxBatchSize int64) error {
+	msg := fmt.Sprintf("requested %d keys, but at most %d keys can be requested at once", requiredKeys, maxBatchSize)
+	return withDetails(codes.InvalidArgument, msg,
+		errorInfo(ReasonBatchTooLarge, map[string]string{MaxBatchSizeMetadata: strconv.FormatInt(maxBatchSize, 10)}),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "RequiredKeys", Description: msg},
+		}},
+	)
+}
+
+// quotaExceededError reports a request that exceeds the quota of client. The client is asked to retry after wait,
+// unless wait is negative because the request can never fit the quota.
+func quotaExceededError(client string, q *quota, wait time.Duration) error {
+	msg := fmt.Sprintf("client %q exceeded its quota of %d keys per %v", client, q.limit, q.period)
+	details := []protoiface.MessageV1{
+		errorInfo(ReasonQuotaExceeded, map[string]string{
+			QuotaLimitMetadata:  strconv.Itoa(q.limit),
+			QuotaPeriodMetadata: q.period.String(),
+		}),
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: "client:" + client, Description: msg},
+		}},
+	}
+	if wait >= 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	}
+	return withDetails(codes.ResourceExhausted, msg, details...)
+}
+
+// withDetails creates a status error with the given details attached.
+func withDetails(code codes.Code, msg string, details ...protoiface.MessageV1) error {
+	st := status.New(code, msg)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// errorInfo describes why a request failed.
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: metadata}
+}
+
+// retryInfo tells clients how long to back off before retrying.
+func retryInfo() *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)}
+}
@@ -0,0 +1,68 @@
+package identity
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// MetadataKey is the gRPC metadata key an authenticated client can name the client it calls on behalf of with.
+const MetadataKey = "x-client-id"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity of an authenticated client.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity of the client of an incoming gRPC call. It is the PrincipalFromContext identity,
+// but an authenticated client can name the client it calls on behalf of with the x-client-id metadata, which is
+// appended as "<identity>/<x-client-id>". An empty string is returned if no identity is known.
+func FromContext(ctx context.Context) string {
+	id, authenticated := principal(ctx)
+	if authenticated {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(MetadataKey); len(ids) > 0 && ids[0] != "" {
+				return id + "/" + ids[0]
+			}
+		}
+	}
+	return id
+}
+
+// PrincipalFromContext returns the identity of the caller of an incoming gRPC call itself, ignoring x-client-id.
+// An authenticated client is identified by the identity stored by NewContext, or else the common name of a verified
+// mTLS client certificate. Any other client is identified by its peer host. An empty string is returned if none of
+// them is known.
+func PrincipalFromContext(ctx context.Context) string {
+	id, _ := principal(ctx)
+	return id
+}
+
+// principal returns the PrincipalFromContext identity and whether the caller is authenticated.
+func principal(ctx context.Context) (string, bool) {
+	p, hasPeer := peer.FromContext(ctx)
+
+	id, ok := ctx.Value(contextKey{}).(string)
+	if (!ok || id == "") && hasPeer {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			id = tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+		}
+	}
+	if id != "" {
+		return id, true
+	}
+
+	if hasPeer && p.Addr != nil {
+		// The port changes with every connection, so only the host identifies a client.
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host, false
+		}
+		return p.Addr.String(), false
+	}
+	return "", false
+}
@@ -0,0 +1,47 @@
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestFromContext(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 40000}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "url-shortener"}}
+	mTLS := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	withMetadata := func(ctx context.Context) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, "metadata-client"))
+	}
+
+	cases := []struct {
+		name      string
+		ctx       context.Context
+		want      string
+		principal string
+	}{
+		{"no identity", context.Background(), "", ""},
+		{"peer host", peer.NewContext(context.Background(), &peer.Peer{Addr: addr}), "10.0.0.1", "10.0.0.1"},
+		{"unauthenticated metadata", withMetadata(peer.NewContext(context.Background(), &peer.Peer{Addr: addr})), "10.0.0.1", "10.0.0.1"},
+		{"mTLS", peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: mTLS}), "url-shortener", "url-shortener"},
+		{"mTLS metadata", withMetadata(peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: mTLS})), "url-shortener/metadata-client", "url-shortener"},
+		{"authenticated", NewContext(peer.NewContext(context.Background(), &peer.Peer{Addr: addr, AuthInfo: mTLS}), "token-client"), "token-client", "token-client"},
+		{"authenticated metadata", NewContext(withMetadata(context.Background()), "token-client"), "token-client/metadata-client", "token-client"},
+	}
+
+	for _, c := range cases {
+		if id := FromContext(c.ctx); id != c.want {
+			t.Errorf("Error incorrect identity for %v: Have %q, want %q.\n", c.name, id, c.want)
+		}
+		if id := PrincipalFromContext(c.ctx); id != c.principal {
+			t.Errorf("Error incorrect principal for %v: Have %q, want %q.\n", c.name, id, c.principal)
+		}
+	}
+}
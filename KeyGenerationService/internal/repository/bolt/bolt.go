@@ -0,0 +1,492 @@
+package bolt
+
+import (
+	"KeyGenerationService/internal/repository"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// unusedBucket maps 'length|key' to nothing, so a cursor walks unused keys shortest first.
+	unusedBucket = []byte("unused")
+	// leasedBucket maps a leased key to its lease ID.
+	leasedBucket = []byte("leased")
+	// leasesBucket maps a lease ID to its expiry, the keys left in a lease are in a nested bucket of the same name.
+	leasesBucket    = []byte("leases")
+	leaseKeysBucket = []byte("lease_keys")
+	usedBucket      = []byte("used")
+	// sequencesBucket maps a big-endian key length to the next unreserved key space index.
+	sequencesBucket = []byte("sequences")
+
+	allBuckets = [][]byte{unusedBucket, leasedBucket, leasesBucket, leaseKeysBucket, usedBucket, sequencesBucket}
+)
+
+// lengthPrefix is the size of the big-endian key length in front of every unused key.
+const lengthPrefix = 2
+
+// DB is a single node Key Generation Service database stored in a bbolt file.
+// Every operation runs in a single bbolt transaction that is synced to disk before it returns,
+// so a crash never loses a claimed key or hands it out twice.
+// Like InMemoryDB, a context is only checked before an operation starts.
+type DB struct {
+	db *bbolt.DB
+}
+
+// Open opens or creates the database file at path. The file is locked while it's open,
+// so only one KGS instance can use it at a time.
+func Open(path string) (*DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+
+	return &DB{db: db}, nil
+}
+
+// Close closes the database file.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// KeyExist checks whether a key exist within DB, whatever its state.
+func (d *DB) KeyExist(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		exists = tx.Bucket(unusedBucket).Get(unusedKey(key)) != nil ||
+			tx.Bucket(leasedBucket).Get([]byte(key)) != nil ||
+			tx.Bucket(usedBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, repository.ErrDatabaseError
+	}
+
+	if !exists {
+		return false, repository.ErrKeyNotFound
+	}
+	return true, nil
+}
+
+// WriteKey stores the given key as unused, unless it's leased or used already.
+// Concurrent writes are batched into one transaction, so the pool isn't filled with one fsync per key.
+func (d *DB) WriteKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.batch(func(tx *bbolt.Tx) error {
+		if inUse(tx, key) {
+			return nil
+		}
+		return tx.Bucket(unusedBucket).Put(unusedKey(key), nil)
+	})
+}
+
+// ReserveKey moves the given key from the unused to the used bucket, or stores it as used if it isn't stored yet.
+func (d *DB) ReserveKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.update(func(tx *bbolt.Tx) error {
+		if inUse(tx, key) {
+			return repository.ErrKeyUsed
+		}
+		if err := tx.Bucket(unusedBucket).Delete(unusedKey(key)); err != nil {
+			return err
+		}
+		return tx.Bucket(usedBucket).Put([]byte(key), nil)
+	})
+}
+
+// inUse checks whether key is leased or used.
+func inUse(tx *bbolt.Tx, key string) bool {
+	return tx.Bucket(leasedBucket).Get([]byte(key)) != nil || tx.Bucket(usedBucket).Get([]byte(key)) != nil
+}
+
+// GetKeys fetches an array of keys, shortest keys first. The fetched keys are moved to the used bucket.
+func (d *DB) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	return d.GetKeysAtLeast(ctx, 0, requiredKeys)
+}
+
+// GetKeysAtLeast fetches an array of keys at least minLength long, shortest keys first.
+func (d *DB) GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	err := d.update(func(tx *bbolt.Tx) error {
+		keys, err := claimKeys(tx, minLength, requiredKeys)
+		if err != nil {
+			return err
+		}
+
+		used := tx.Bucket(usedBucket)
+		for _, key := range keys {
+			if err := used.Put([]byte(key), nil); err != nil {
+				return err
+			}
+		}
+		result = keys
+		return nil
+	})
+	if err != nil {
+		return []string{}, err
+	}
+
+	return result, nil
+}
+
+// claimKeys removes requiredKeys unused keys at least minLength long, shortest keys first, and returns them.
+// The caller stores them elsewhere within the same transaction.
+func claimKeys(tx *bbolt.Tx, minLength int, requiredKeys int) ([]string, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return nil, repository.ErrKeyOOR
+	}
+
+	unused := tx.Bucket(unusedBucket)
+	result := make([]string, 0, requiredKeys)
+	var claimed [][]byte
+
+	c := unused.Cursor()
+	if minLength < 0 {
+		minLength = 0
+	}
+	first := binary.BigEndian.AppendUint16(nil, uint16(minLength))
+	for k, _ := c.Seek(first); k != nil && len(result) < requiredKeys; k, _ = c.Next() {
+		result = append(result, string(k[lengthPrefix:]))
+		claimed = append(claimed, bytes.Clone(k))
+	}
+
+	// Cannot have requiredKeys greater than what we have in the unused bucket. Returning an error rolls the
+	// transaction back, so nothing is claimed.
+	if len(result) < requiredKeys {
+		return nil, repository.ErrKeyOOR
+	}
+
+	for _, k := range claimed {
+		if err := unused.Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// KeyCount returns the amount of unused keys.
+func (d *DB) KeyCount(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var count int
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		count = tx.Bucket(unusedBucket).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		return 0, repository.ErrDatabaseError
+	}
+	return count, nil
+}
+
+// Stats counts the unused, leased and used keys, in total and per key length.
+func (d *DB) Stats(ctx context.Context) (repository.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.Stats{}, err
+	}
+
+	stats := repository.Stats{ByLength: make(map[int]repository.Counts)}
+	count := func(b *bbolt.Bucket, prefix int, field func(*repository.Counts) *int) error {
+		return b.ForEach(func(k, _ []byte) error {
+			length := len(k) - prefix
+			counts := stats.ByLength[length]
+			*field(&counts)++
+			stats.ByLength[length] = counts
+			*field(&stats.Counts)++
+			return nil
+		})
+	}
+
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		if err := count(tx.Bucket(unusedBucket), lengthPrefix, func(c *repository.Counts) *int { return &c.Unused }); err != nil {
+			return err
+		}
+		if err := count(tx.Bucket(leasedBucket), 0, func(c *repository.Counts) *int { return &c.Leased }); err != nil {
+			return err
+		}
+		return count(tx.Bucket(usedBucket), 0, func(c *repository.Counts) *int { return &c.Used })
+	})
+	if err != nil {
+		return repository.Stats{}, repository.ErrDatabaseError
+	}
+
+	return stats, nil
+}
+
+// LeaseKeys moves requiredKeys unused keys into a new lease that expires after ttl.
+func (d *DB) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	return d.LeaseKeysAtLeast(ctx, 0, requiredKeys, ttl)
+}
+
+// LeaseKeysAtLeast moves requiredKeys unused keys at least minLength long into a new lease that expires after ttl.
+func (d *DB) LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.Lease{}, err
+	}
+
+	id, err := repository.NewLeaseID()
+	if err != nil {
+		return repository.Lease{}, repository.ErrDatabaseError
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	var keys []string
+	err = d.update(func(tx *bbolt.Tx) error {
+		keys, err = claimKeys(tx, minLength, requiredKeys)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(leasesBucket).Put([]byte(id), encodeTime(expiresAt)); err != nil {
+			return err
+		}
+		leaseKeys, err := tx.Bucket(leaseKeysBucket).CreateBucket([]byte(id))
+		if err != nil {
+			return err
+		}
+		leased := tx.Bucket(leasedBucket)
+		for _, key := range keys {
+			if err := leased.Put([]byte(key), []byte(id)); err != nil {
+				return err
+			}
+			if err := leaseKeys.Put([]byte(key), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return repository.Lease{}, err
+	}
+
+	return repository.Lease{ID: id, Keys: keys, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmKeys moves the given leased keys to the used bucket.
+func (d *DB) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.update(func(tx *bbolt.Tx) error {
+		return settleKeys(tx, leaseID, keys, false, func(key []byte) error {
+			return tx.Bucket(usedBucket).Put(key, nil)
+		})
+	})
+}
+
+// ReleaseKeys moves the given leased keys back to the unused bucket, or every key left in the lease if keys is empty.
+func (d *DB) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return d.update(func(tx *bbolt.Tx) error {
+		return settleKeys(tx, leaseID, keys, len(keys) == 0, func(key []byte) error {
+			return tx.Bucket(unusedBucket).Put(unusedKey(string(key)), nil)
+		})
+	})
+}
+
+// settleKeys moves the given keys, or every key left in the lease if all is set, out of a lease with store.
+// Returning an error rolls the transaction back, so either every key is moved or none is.
+func settleKeys(tx *bbolt.Tx, leaseID string, keys []string, all bool, store func(key []byte) error) error {
+	expiry := tx.Bucket(leasesBucket).Get([]byte(leaseID))
+	if expiry == nil || !time.Now().Before(decodeTime(expiry)) {
+		return repository.ErrLeaseNotFound
+	}
+
+	leaseKeys := tx.Bucket(leaseKeysBucket).Bucket([]byte(leaseID))
+	var settle [][]byte
+	if all {
+		err := leaseKeys.ForEach(func(k, _ []byte) error {
+			settle = append(settle, bytes.Clone(k))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, key := range keys {
+			if leaseKeys.Get([]byte(key)) == nil {
+				return repository.ErrKeyNotLeased
+			}
+			settle = append(settle, []byte(key))
+		}
+	}
+
+	for _, key := range settle {
+		// A key given twice is already settled.
+		if leaseKeys.Get(key) == nil {
+			continue
+		}
+		if err := leaseKeys.Delete(key); err != nil {
+			return err
+		}
+		if err := tx.Bucket(leasedBucket).Delete(key); err != nil {
+			return err
+		}
+		if err := store(key); err != nil {
+			return err
+		}
+	}
+
+	if k, _ := leaseKeys.Cursor().First(); k == nil {
+		return deleteLease(tx, []byte(leaseID))
+	}
+	return nil
+}
+
+// ExpireLeases moves the keys of every expired lease back to the unused bucket.
+func (d *DB) ExpireLeases(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var expired int
+	err := d.update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		var ids [][]byte
+		err := tx.Bucket(leasesBucket).ForEach(func(id, expiry []byte) error {
+			if !now.Before(decodeTime(expiry)) {
+				ids = append(ids, bytes.Clone(id))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		unused, leased := tx.Bucket(unusedBucket), tx.Bucket(leasedBucket)
+		for _, id := range ids {
+			err := tx.Bucket(leaseKeysBucket).Bucket(id).ForEach(func(key, _ []byte) error {
+				expired++
+				if err := leased.Delete(key); err != nil {
+					return err
+				}
+				return unused.Put(unusedKey(string(key)), nil)
+			})
+			if err != nil {
+				return err
+			}
+			if err := deleteLease(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// deleteLease removes a lease and its key bucket.
+func deleteLease(tx *bbolt.Tx, leaseID []byte) error {
+	if err := tx.Bucket(leasesBucket).Delete(leaseID); err != nil {
+		return err
+	}
+	return tx.Bucket(leaseKeysBucket).DeleteBucket(leaseID)
+}
+
+// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+func (d *DB) ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, repository.ErrKeyOOR
+	}
+
+	var start uint64
+	err := d.update(func(tx *bbolt.Tx) error {
+		sequences := tx.Bucket(sequencesBucket)
+		length := binary.BigEndian.AppendUint16(nil, uint16(keyLength))
+		if next := sequences.Get(length); next != nil {
+			start = binary.BigEndian.Uint64(next)
+		}
+		if n == 0 {
+			return nil
+		}
+		return sequences.Put(length, binary.BigEndian.AppendUint64(nil, start+uint64(n)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return start, nil
+}
+
+// update runs fn in a read-write transaction. Repository errors returned by fn are passed through,
+// anything else is reported as repository.ErrDatabaseError.
+func (d *DB) update(fn func(tx *bbolt.Tx) error) error {
+	return txError(d.db.Update(fn))
+}
+
+// batch runs fn in a read-write transaction shared with concurrent calls, like update.
+// fn may run more than once if another call of the batch fails, so it must be idempotent.
+func (d *DB) batch(fn func(tx *bbolt.Tx) error) error {
+	return txError(d.db.Batch(fn))
+}
+
+// txError passes through repository errors, and reports anything else as repository.ErrDatabaseError.
+func txError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, repoErr := range []error{repository.ErrKeyOOR, repository.ErrLeaseNotFound, repository.ErrKeyNotLeased, repository.ErrKeyUsed} {
+		if errors.Is(err, repoErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+}
+
+// unusedKey prefixes key with its big-endian length, so unused keys sort by length first.
+func unusedKey(key string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(key))), key...)
+}
+
+func encodeTime(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
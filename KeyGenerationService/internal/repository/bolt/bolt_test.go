@@ -0,0 +1,74 @@
+package bolt
+
+import (
+	"KeyGenerationService/internal/repository"
+	"KeyGenerationService/internal/repository/repositorytest"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) (*DB, string) {
+	path := filepath.Join(t.TempDir(), "kgs.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error opening database: %v.\n", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, path
+}
+
+func TestOpen(t *testing.T) {
+	db, path := openTestDB(t)
+	ctx := context.Background()
+
+	// 1. The file is locked while it's open.
+	if _, err := Open(path); !errors.Is(err, repository.ErrDatabaseError) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrDatabaseError)
+	}
+
+	// 2. Keys and their states survive a restart.
+	for _, key := range []string{"key1", "key2", "key3", "key4"} {
+		if err := db.WriteKey(ctx, key); err != nil {
+			t.Errorf("Error writing key: %v.\n", err)
+		}
+	}
+	if _, err := db.GetKeys(ctx, 1); err != nil {
+		t.Errorf("Error getting keys: %v.\n", err)
+	}
+	if _, err := db.LeaseKeys(ctx, 1, time.Minute); err != nil {
+		t.Errorf("Error leasing keys: %v.\n", err)
+	}
+	if _, err := db.ReserveIndexes(ctx, 4, 10); err != nil {
+		t.Errorf("Error reserving indexes: %v.\n", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Error closing database: %v.\n", err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error reopening database: %v.\n", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Errorf("Error getting stats: %v.\n", err)
+	}
+	if want := (repository.Counts{Unused: 2, Leased: 1, Used: 1}); stats.Counts != want {
+		t.Errorf("Error incorrect stats: Have %+v, want %+v.\n", stats.Counts, want)
+	}
+	if next, _ := db.ReserveIndexes(ctx, 4, 0); next != 10 {
+		t.Errorf("Error incorrect next index: Have %v, want %v.\n", next, 10)
+	}
+}
+
+func TestDB_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.KGSDatabase {
+		db, _ := openTestDB(t)
+		return db
+	})
+}
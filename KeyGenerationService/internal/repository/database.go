@@ -2,18 +2,79 @@ package repository
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"time"
 )
 
 // KGSDatabase is the interface that wraps writing and fetching keys from a Key Generation Service Database.
 type KGSDatabase interface {
 	KeyExist(context.Context, string) (bool, error)
+	// WriteKey stores a new unused key. A key that's leased or used already, such as a reserved one, is left as is.
 	WriteKey(context.Context, string) error
+	// GetKeys claims keys of any length, shortest keys first, so keys of an old key length are drained first.
 	GetKeys(context.Context, int) ([]string, error)
+	// GetKeysAtLeast claims keys at least minLength long, shortest keys first.
+	GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error)
+	KeyCount(context.Context) (int, error)
+	Stats(context.Context) (Stats, error)
+
+	// LeaseKeys hands out keys that return to the pool unless they are confirmed before the lease expires.
+	// Like GetKeys, shortest keys are leased first.
+	LeaseKeys(context.Context, int, time.Duration) (Lease, error)
+	// LeaseKeysAtLeast leases keys at least minLength long, shortest keys first.
+	LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (Lease, error)
+	// ConfirmKeys marks leased keys as used.
+	ConfirmKeys(context.Context, string, []string) error
+	// ReleaseKeys returns leased keys to the pool, releasing every key left in the lease if none are given.
+	ReleaseKeys(context.Context, string, []string) error
+	// ExpireLeases returns the keys of every expired lease to the pool and reports how many keys were returned.
+	ExpireLeases(context.Context) (int, error)
+
+	// ReserveKey marks the given key as used, whether it's unused or not stored yet, so clients can pick their own key.
+	// It fails with ErrKeyUsed if the key is leased or used already.
+	ReserveKey(context.Context, string) error
+
+	// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+	// Reserving 0 indexes returns the next unreserved index without reserving anything.
+	ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error)
+}
+
+// Counts is how many keys are in each state.
+type Counts struct {
+	Unused int
+	Leased int
+	Used   int
+}
+
+// Stats is a snapshot of how many keys a database holds in each state, in total and per key length.
+type Stats struct {
+	Counts
+	ByLength map[int]Counts
+}
+
+// Lease is a batch of keys handed out to a client until ExpiresAt.
+type Lease struct {
+	ID        string
+	Keys      []string
+	ExpiresAt time.Time
 }
 
 var (
 	ErrKeyNotFound   = errors.New("error desired key isn't found in database")
 	ErrDatabaseError = errors.New("error malfunctioning of connecting to or using resource from a database")
 	ErrKeyOOR        = errors.New("error key out of range")
+	ErrLeaseNotFound = errors.New("error lease isn't found or has expired")
+	ErrKeyNotLeased  = errors.New("error key doesn't belong to the lease")
+	ErrKeyUsed       = errors.New("error key is already leased or used")
 )
+
+// NewLeaseID generates a random, unguessable lease ID.
+func NewLeaseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
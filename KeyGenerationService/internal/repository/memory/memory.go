@@ -3,77 +3,356 @@ package memory
 import (
 	"KeyGenerationService/internal/repository"
 	"context"
+	"sort"
 	"sync"
+	"time"
 )
 
 // InMemoryDB mocks the database for Key Generation Service.
+// Operations are instant, so a context is only checked before an operation starts.
 type InMemoryDB struct {
 	// Since our read and write are concurrent, use sync.Map instead of normal map and locks.
-	Keys     sync.Map
-	UsedKeys sync.Map
+	// A key is stored in its new map before it's deleted from the old one, so KeyExist never misses a moving key.
+	Keys       sync.Map
+	UsedKeys   sync.Map
+	LeasedKeys sync.Map
+
+	// claimMu serializes claims, writes and reservations, so collecting and removing a batch of keys from Keys is a
+	// single step, and a reserved key is never written to Keys again.
+	claimMu sync.Mutex
+
+	// leases is guarded by leaseMu, since confirming or releasing a lease spans several keys.
+	leaseMu sync.Mutex
+	leases  map[string]*lease
+
+	// indexes holds the next unreserved key space index per key length, guarded by indexMu.
+	indexMu sync.Mutex
+	indexes map[int]uint64
+}
+
+// lease holds the keys of a repository.Lease that are neither confirmed nor released yet.
+type lease struct {
+	keys      map[string]struct{}
+	expiresAt time.Time
 }
 
 // New creates a new instance of InMemoryDB.
 func New() (*InMemoryDB, error) {
 	return &InMemoryDB{
-		Keys:     sync.Map{},
-		UsedKeys: sync.Map{},
+		Keys:       sync.Map{},
+		UsedKeys:   sync.Map{},
+		LeasedKeys: sync.Map{},
+		leases:     make(map[string]*lease),
+		indexes:    make(map[int]uint64),
 	}, nil
 }
 
 // KeyExist checks whether a key exist within InMemoryDB.
 func (i *InMemoryDB) KeyExist(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
 	if _, ok := i.Keys.Load(key); ok {
 		return true, nil
 	}
 	if _, ok := i.UsedKeys.Load(key); ok {
 		return true, nil
 	}
+	if _, ok := i.LeasedKeys.Load(key); ok {
+		return true, nil
+	}
 	return false, repository.ErrKeyNotFound
 }
 
-// WriteKey stores the given key to InMemoryDB.
+// WriteKey stores the given key to InMemoryDB, unless it's leased or used already.
 func (i *InMemoryDB) WriteKey(ctx context.Context, key string) error {
-	i.Keys.Store(key, struct{}{})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.claimMu.Lock()
+	defer i.claimMu.Unlock()
+
+	if !i.inUse(key) {
+		i.Keys.Store(key, struct{}{})
+	}
 
 	return nil
 }
 
-// GetKeys fetches an array of keys.
+// ReserveKey moves the given key from Keys to UsedKeys, or stores it in UsedKeys if it isn't stored yet.
+func (i *InMemoryDB) ReserveKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.claimMu.Lock()
+	defer i.claimMu.Unlock()
+
+	if i.inUse(key) {
+		return repository.ErrKeyUsed
+	}
+	i.UsedKeys.Store(key, struct{}{})
+	i.Keys.Delete(key)
+
+	return nil
+}
+
+// inUse checks whether key is leased or used.
+func (i *InMemoryDB) inUse(key string) bool {
+	if _, ok := i.LeasedKeys.Load(key); ok {
+		return true
+	}
+	_, ok := i.UsedKeys.Load(key)
+	return ok
+}
+
+// GetKeys fetches an array of keys, shortest keys first.
 // The fetched keys are considered used and will be moved to UsedKeys for further usage.
 func (i *InMemoryDB) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	return i.GetKeysAtLeast(ctx, 0, requiredKeys)
+}
+
+// GetKeysAtLeast fetches an array of keys at least minLength long, shortest keys first.
+func (i *InMemoryDB) GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return i.claimKeys(minLength, requiredKeys, &i.UsedKeys)
+}
+
+// claimKeys moves requiredKeys keys at least minLength long from Keys to dst, shortest keys first, and returns them.
+// Only claims remove keys from Keys, so with claimMu held every key seen by Range is still there when it's moved.
+func (i *InMemoryDB) claimKeys(minLength int, requiredKeys int, dst *sync.Map) ([]string, error) {
 	// Cannot have negative or zero requiredKeys.
 	if requiredKeys <= 0 {
 		return []string{}, repository.ErrKeyOOR
 	}
 
-	// Cannot have requiredKeys greater than what we have in 'keys'.
-	// This shouldn't happen since we always assume that we have enough keys in pool waiting.
-	var mapLength int
-	i.Keys.Range(func(_, _ any) bool {
-		mapLength++
+	i.claimMu.Lock()
+	defer i.claimMu.Unlock()
+
+	// Group the candidate keys by length.
+	var candidates int
+	byLength := make(map[int][]string)
+	i.Keys.Range(func(key, _ any) bool {
+		if k := key.(string); len(k) >= minLength {
+			byLength[len(k)] = append(byLength[len(k)], k)
+			candidates++
+		}
 		return true
 	})
 
-	if requiredKeys > mapLength {
+	// Cannot have requiredKeys greater than what we have in 'keys'.
+	// This shouldn't happen since we always assume that we have enough keys in pool waiting.
+	if requiredKeys > candidates {
 		return []string{}, repository.ErrKeyOOR
 	}
 
+	lengths := make([]int, 0, len(byLength))
+	for length := range byLength {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+
 	// Create an array that stores all fetched keys.
-	result := make([]string, requiredKeys)
-	// Get keys randomly, and move used keys to used map.
-	j := 0
-	i.Keys.Range(func(key, value any) bool {
-		if j == requiredKeys {
-			return false
+	result := make([]string, 0, requiredKeys)
+	for _, length := range lengths {
+		for _, key := range byLength[length] {
+			if len(result) == requiredKeys {
+				break
+			}
+			result = append(result, key)
+			dst.Store(key, struct{}{})
+			i.Keys.Delete(key)
 		}
+	}
+	return result, nil
+}
 
-		result[j] = key.(string)
-		i.Keys.Delete(key)
-		i.UsedKeys.Store(key, struct{}{})
-		j++
+// KeyCount returns the amount of unused keys in InMemoryDB.
+func (i *InMemoryDB) KeyCount(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
+	var count int
+	i.Keys.Range(func(_, _ any) bool {
+		count++
 		return true
 	})
-	return result, nil
+	return count, nil
+}
+
+// Stats counts the keys in Keys, LeasedKeys and UsedKeys, in total and per key length.
+func (i *InMemoryDB) Stats(ctx context.Context) (repository.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.Stats{}, err
+	}
+
+	stats := repository.Stats{ByLength: make(map[int]repository.Counts)}
+
+	count := func(m *sync.Map, field func(*repository.Counts) *int) {
+		m.Range(func(key, _ any) bool {
+			length := len(key.(string))
+			counts := stats.ByLength[length]
+			*field(&counts)++
+			stats.ByLength[length] = counts
+			*field(&stats.Counts)++
+			return true
+		})
+	}
+	count(&i.Keys, func(c *repository.Counts) *int { return &c.Unused })
+	count(&i.LeasedKeys, func(c *repository.Counts) *int { return &c.Leased })
+	count(&i.UsedKeys, func(c *repository.Counts) *int { return &c.Used })
+
+	return stats, nil
+}
+
+// LeaseKeys moves requiredKeys keys from Keys to LeasedKeys under a new lease that expires after ttl.
+func (i *InMemoryDB) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	return i.LeaseKeysAtLeast(ctx, 0, requiredKeys, ttl)
+}
+
+// LeaseKeysAtLeast moves requiredKeys keys at least minLength long from Keys to LeasedKeys under a new lease that
+// expires after ttl.
+func (i *InMemoryDB) LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.Lease{}, err
+	}
+
+	id, err := repository.NewLeaseID()
+	if err != nil {
+		return repository.Lease{}, repository.ErrDatabaseError
+	}
+
+	i.leaseMu.Lock()
+	defer i.leaseMu.Unlock()
+
+	keys, err := i.claimKeys(minLength, requiredKeys, &i.LeasedKeys)
+	if err != nil {
+		return repository.Lease{}, err
+	}
+
+	l := &lease{keys: make(map[string]struct{}, len(keys)), expiresAt: time.Now().Add(ttl)}
+	for _, key := range keys {
+		l.keys[key] = struct{}{}
+	}
+	i.leases[id] = l
+
+	return repository.Lease{ID: id, Keys: keys, ExpiresAt: l.expiresAt}, nil
+}
+
+// ConfirmKeys moves the given leased keys to UsedKeys.
+func (i *InMemoryDB) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return i.settleKeys(leaseID, keys, &i.UsedKeys)
+}
+
+// ReleaseKeys moves the given leased keys back to Keys, or every key left in the lease if keys is empty.
+func (i *InMemoryDB) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		i.leaseMu.Lock()
+		defer i.leaseMu.Unlock()
+
+		l, ok := i.activeLease(leaseID)
+		if !ok {
+			return repository.ErrLeaseNotFound
+		}
+		for key := range l.keys {
+			i.Keys.Store(key, struct{}{})
+			i.LeasedKeys.Delete(key)
+		}
+		delete(i.leases, leaseID)
+		return nil
+	}
+
+	return i.settleKeys(leaseID, keys, &i.Keys)
+}
+
+// settleKeys moves the given keys out of a lease into dst. Either every key is moved or none is.
+func (i *InMemoryDB) settleKeys(leaseID string, keys []string, dst *sync.Map) error {
+	i.leaseMu.Lock()
+	defer i.leaseMu.Unlock()
+
+	l, ok := i.activeLease(leaseID)
+	if !ok {
+		return repository.ErrLeaseNotFound
+	}
+	for _, key := range keys {
+		if _, ok := l.keys[key]; !ok {
+			return repository.ErrKeyNotLeased
+		}
+	}
+
+	for _, key := range keys {
+		delete(l.keys, key)
+		dst.Store(key, struct{}{})
+		i.LeasedKeys.Delete(key)
+	}
+	if len(l.keys) == 0 {
+		delete(i.leases, leaseID)
+	}
+	return nil
+}
+
+// activeLease returns the lease with the given ID if it hasn't expired. leaseMu must be held.
+func (i *InMemoryDB) activeLease(leaseID string) (*lease, bool) {
+	l, ok := i.leases[leaseID]
+	if !ok || !time.Now().Before(l.expiresAt) {
+		return nil, false
+	}
+	return l, true
+}
+
+// ExpireLeases moves the keys of every expired lease back to Keys.
+func (i *InMemoryDB) ExpireLeases(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	i.leaseMu.Lock()
+	defer i.leaseMu.Unlock()
+
+	now := time.Now()
+	var expired int
+	for id, l := range i.leases {
+		if now.Before(l.expiresAt) {
+			continue
+		}
+		for key := range l.keys {
+			i.Keys.Store(key, struct{}{})
+			i.LeasedKeys.Delete(key)
+			expired++
+		}
+		delete(i.leases, id)
+	}
+	return expired, nil
+}
+
+// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+func (i *InMemoryDB) ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, repository.ErrKeyOOR
+	}
+
+	i.indexMu.Lock()
+	defer i.indexMu.Unlock()
+
+	start := i.indexes[keyLength]
+	i.indexes[keyLength] = start + uint64(n)
+	return start, nil
 }
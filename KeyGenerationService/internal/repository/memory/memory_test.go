@@ -2,9 +2,13 @@ package memory
 
 import (
 	"KeyGenerationService/internal/repository"
+	"KeyGenerationService/internal/repository/repositorytest"
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -17,91 +21,99 @@ func TestNew(t *testing.T) {
 	}
 }
 
-func TestInMemoryDB_KeyExist(t *testing.T) {
-	db, err := New()
-	if err != nil {
-		t.Errorf("Error creating a new in-memory database: %v.\n", err)
-	}
-	ctx := context.Background()
-
-	testKey := "1234"
-
-	// 1. Fetch key that doesn't exist.
-	ok, err := db.KeyExist(ctx, testKey)
-	if !errors.Is(err, repository.ErrKeyNotFound) {
-		t.Errorf("Error wrong error: Have %v, want %v.\n", err, repository.ErrKeyNotFound)
-	}
-
-	if ok {
-		t.Errorf("Error shouldn't have fetched key that doesn't exist.\n")
-	}
-
-	// 2. Store key in keys, check key existence.
-	db.Keys.Store(testKey, struct{}{})
-	ok, err = db.KeyExist(ctx, testKey)
-	if err != nil && !ok {
-		t.Errorf("Error checking key existence: %v.\n", err)
-	}
-
-	db.Keys.Delete(testKey)
-
-	// 3. Store key in UsedKeys, check key existence.
-	db.UsedKeys.Store(testKey, struct{}{})
-	ok, err = db.KeyExist(ctx, testKey)
-	if err != nil && !ok {
-		t.Errorf("Error checking key existence: %v.\n", err)
-	}
-
-	db.UsedKeys.Delete(testKey)
+func TestInMemoryDB_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.KGSDatabase {
+		db, err := New()
+		if err != nil {
+			t.Fatalf("Error creating a new in-memory database: %v.\n", err)
+		}
+		return db
+	})
 }
 
-func TestInMemoryDB_WriteKey(t *testing.T) {
+// Run with -race. Many goroutines claim batches of every size while keys are still being written,
+// and every key must be handed out exactly once.
+func TestInMemoryDB_ClaimKeys_Race(t *testing.T) {
 	inMemory, err := New()
 	if err != nil {
-		t.Errorf("Error creating a new in-memory database: %v.\n", err)
+		t.Fatalf("Error creating a new in-memory database: %v.\n", err)
 	}
 	ctx := context.Background()
 
-	testKey := "1234"
+	const writers, claimers, keysPerWriter = 4, 32, 1000
 
-	err = inMemory.WriteKey(ctx, testKey)
-	if err != nil {
-		t.Errorf("Error writing key to in-memory database: %v.\n", err)
-	}
-
-	_, ok := inMemory.Keys.Load(testKey)
-	if !ok {
-		t.Errorf("Error written key is not in in-memory database.\n")
-	}
-
-}
-
-func TestInMemoryDB_GetKeys(t *testing.T) {
-	inMemory, err := New()
-	if err != nil {
-		t.Errorf("Error creating a new in-memory database: %v.\n", err)
+	var writing sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writing.Add(1)
+		go func(w int) {
+			defer writing.Done()
+			for k := 0; k < keysPerWriter; k++ {
+				_ = inMemory.WriteKey(ctx, fmt.Sprintf("%d-%04d", w, k))
+			}
+		}(w)
 	}
-	ctx := context.Background()
-
-	testKeys := []string{"0123", "1234", "2345", "3456", "4567", "5678", "6789", "7890"}
-	for _, key := range testKeys {
-		inMemory.Keys.Store(key, struct{}{})
+	writingDone := make(chan struct{})
+	go func() {
+		writing.Wait()
+		close(writingDone)
+	}()
+
+	var claiming sync.WaitGroup
+	results := make(chan []string, claimers)
+	for c := 0; c < claimers; c++ {
+		claiming.Add(1)
+		go func(c int) {
+			defer claiming.Done()
+			var claimed []string
+			for n := 1; ; n = n%10 + 1 {
+				var keys []string
+				var err error
+				if c%2 == 0 {
+					keys, err = inMemory.GetKeys(ctx, n)
+				} else {
+					var lease repository.Lease
+					lease, err = inMemory.LeaseKeys(ctx, n, time.Minute)
+					keys = lease.Keys
+				}
+
+				if errors.Is(err, repository.ErrKeyOOR) {
+					// Stop once the writers are done and the pool can't hold a batch anymore.
+					select {
+					case <-writingDone:
+						results <- claimed
+						return
+					default:
+						continue
+					}
+				}
+				if err != nil {
+					t.Errorf("Error claiming keys: %v.\n", err)
+				}
+				if len(keys) != n {
+					t.Errorf("Error incorrect batch size: Have %v, want %v.\n", len(keys), n)
+				}
+				claimed = append(claimed, keys...)
+			}
+		}(c)
 	}
-
-	// Test different requiredKeys.
-	// TODO: Test when key required keys is greater than the amount of valid keys left.
-	cases := []int{-1, 0, 1, 3, 10}
-
-	for _, requiredKeys := range cases {
-		result, err := inMemory.GetKeys(ctx, requiredKeys)
-		if err != nil {
-			if (requiredKeys <= 0 || requiredKeys > len(testKeys)) && !errors.Is(err, repository.ErrKeyOOR) {
-				t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	claiming.Wait()
+	close(results)
+
+	seen := make(map[string]struct{}, writers*keysPerWriter)
+	for claimed := range results {
+		for _, key := range claimed {
+			if key == "" {
+				t.Errorf("Error empty key handed out.\n")
 			}
-		} else {
-			if len(result) != requiredKeys {
-				t.Errorf("Error get keys error: Invalid keys length.\n")
+			if _, ok := seen[key]; ok {
+				t.Errorf("Error key %v is handed out more than once.\n", key)
 			}
+			seen[key] = struct{}{}
 		}
 	}
+
+	left, _ := inMemory.KeyCount(ctx)
+	if len(seen)+left != writers*keysPerWriter {
+		t.Errorf("Error keys went missing: Have %v claimed and %v left, want %v in total.\n", len(seen), left, writers*keysPerWriter)
+	}
 }
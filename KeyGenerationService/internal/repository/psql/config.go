@@ -0,0 +1,91 @@
+package psql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidConfig = errors.New("error invalid database config")
+
+// Config describes how to connect to PostgreSQL.
+// DSN accepts either a 'key=value' connection string or a 'postgres://' URL, every other non-zero field overrides it.
+// Fields left empty fall back to the DSN, then to the PG* environment variables and libpq defaults.
+type Config struct {
+	DSN string
+
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+
+	// SSLMode is one of disable, allow, prefer, require, verify-ca or verify-full.
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime configure the connection pool, zero keeps the database/sql defaults.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// StatementTimeout aborts any statement running longer than this, zero disables it.
+	StatementTimeout time.Duration
+}
+
+// connString builds the lib/pq connection string of c.
+func (c Config) connString() (string, error) {
+	if c.Port < 0 || c.MaxOpenConns < 0 || c.MaxIdleConns < 0 || c.ConnMaxLifetime < 0 || c.StatementTimeout < 0 {
+		return "", fmt.Errorf("%w: negative value", ErrInvalidConfig)
+	}
+
+	dsn := strings.TrimSpace(c.DSN)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := pq.ParseURL(dsn)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		dsn = parsed
+	}
+
+	// lib/pq keeps the last value of a repeated key, so the fields are appended after the DSN.
+	params := []string{}
+	if dsn != "" {
+		params = append(params, dsn)
+	}
+	add := func(key, value string) {
+		if value != "" {
+			params = append(params, key+"="+quote(value))
+		}
+	}
+	add("host", c.Host)
+	if c.Port > 0 {
+		add("port", strconv.Itoa(c.Port))
+	}
+	add("user", c.User)
+	add("password", c.Password)
+	add("dbname", c.Database)
+	add("sslmode", c.SSLMode)
+	add("sslrootcert", c.SSLRootCert)
+	add("sslcert", c.SSLCert)
+	add("sslkey", c.SSLKey)
+	if c.StatementTimeout > 0 {
+		// Sent as a run-time parameter on connect, so it holds for every connection of the pool.
+		add("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// quote escapes a connection string value, see https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING.
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
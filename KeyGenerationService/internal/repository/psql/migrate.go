@@ -0,0 +1,225 @@
+package psql
+
+import (
+	"KeyGenerationService/internal/repository"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationLockID is the advisory lock held while migrating, so concurrent KGS instances migrate one at a time.
+const migrationLockID = 7160533
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrInvalidMigration = errors.New("error invalid migration file")
+	ErrMigrationSteps   = errors.New("error cannot migrate a negative amount of steps")
+)
+
+// migration is a single schema version, with the SQL that applies it and the SQL that reverts it.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// loadMigrations reads the embedded migrations, named '<version>_<name>.up.sql' and '<version>_<name>.down.sql',
+// ordered by version. Versions must start at 1 and have no gaps.
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, file := range files {
+		base := path.Base(file)
+		versionPart, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, base)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, base)
+		}
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version}
+			byVersion[version] = m
+		}
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			m.name, m.up = strings.TrimSuffix(rest, ".up.sql"), string(content)
+		case strings.HasSuffix(rest, ".down.sql"):
+			m.down = string(content)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, base)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	for i, m := range migrations {
+		if m.version != i+1 || m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("%w: version %d", ErrInvalidMigration, m.version)
+		}
+	}
+	return migrations, nil
+}
+
+// MigrationVersion returns the latest applied schema version, 0 if none is applied.
+func (d *DB) MigrationVersion(ctx context.Context) (int, error) {
+	if err := d.createMigrationsTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var version int
+	row := d.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&version); err != nil {
+		return 0, dbError(ctx)
+	}
+	return version, nil
+}
+
+// MigrateUp applies up to steps pending migrations, or every pending migration if steps is 0,
+// and returns the schema version it ended at.
+func (d *DB) MigrateUp(ctx context.Context, steps int) (int, error) {
+	if steps < 0 {
+		return 0, ErrMigrationSteps
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if steps == 0 {
+		steps = len(migrations)
+	}
+
+	return d.migrate(ctx, steps, func(version int) (migration, bool) {
+		if version >= len(migrations) {
+			return migration{}, false
+		}
+		return migrations[version], true
+	})
+}
+
+// MigrateDown reverts the latest steps applied migrations and returns the schema version it ended at.
+func (d *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	if steps < 0 {
+		return 0, ErrMigrationSteps
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	return d.migrate(ctx, steps, func(version int) (migration, bool) {
+		if version == 0 || version > len(migrations) {
+			return migration{}, false
+		}
+		// A negative version tells migrate to revert instead of apply.
+		m := migrations[version-1]
+		m.version = -m.version
+		return m, true
+	})
+}
+
+// migrate runs up to steps migrations, one transaction each.
+// next returns the migration to run at the current schema version, and false once there is nothing left to run.
+// Down migrations are returned with a negated version.
+func (d *DB) migrate(ctx context.Context, steps int, next func(version int) (migration, bool)) (int, error) {
+	if err := d.createMigrationsTable(ctx); err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < steps; i++ {
+		ran, err := d.migrateStep(ctx, next)
+		if err != nil {
+			return 0, err
+		}
+		if !ran {
+			break
+		}
+	}
+
+	return d.MigrationVersion(ctx)
+}
+
+// migrateStep runs a single migration in a transaction holding the migration lock, and reports whether it ran one.
+func (d *DB) migrateStep(ctx context.Context, next func(version int) (migration, bool)) (bool, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		return false, dbError(ctx)
+	}
+
+	// Read the version after taking the lock, another instance may have migrated meanwhile.
+	var version int
+	row := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&version); err != nil {
+		return false, dbError(ctx)
+	}
+
+	m, ok := next(version)
+	if !ok {
+		return false, nil
+	}
+
+	if m.version > 0 {
+		if _, err := tx.ExecContext(ctx, m.up); err != nil {
+			return false, fmt.Errorf("%w: migration %d up: %v", repository.ErrDatabaseError, m.version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+			return false, dbError(ctx)
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, m.down); err != nil {
+			return false, fmt.Errorf("%w: migration %d down: %v", repository.ErrDatabaseError, -m.version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", -m.version); err != nil {
+			return false, dbError(ctx)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, dbError(ctx)
+	}
+	return true, nil
+}
+
+// createMigrationsTable creates the table recording applied schema versions if it doesn't exist yet.
+func (d *DB) createMigrationsTable(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return dbError(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v.\n", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("Error no migrations embedded.\n")
+	}
+
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("Error migrations should be consecutive: Have %v, want %v.\n", m.version, i+1)
+		}
+		if m.name == "" || m.up == "" || m.down == "" {
+			t.Errorf("Error migration %d should have a name, an up and a down step.\n", m.version)
+		}
+	}
+}
+
+// TestDB_Migrate leaves the schema fully migrated.
+func TestDB_Migrate(t *testing.T) {
+	db := newTestDB(t)
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v.\n", err)
+	}
+	latest := len(migrations)
+	ctx := context.Background()
+
+	// 1. Migrating up is idempotent.
+	for i := 0; i < 2; i++ {
+		version, err := db.MigrateUp(ctx, 0)
+		if err != nil {
+			t.Fatalf("Error migrating up: %v.\n", err)
+		}
+		if version != latest {
+			t.Errorf("Error incorrect version: Have %v, want %v.\n", version, latest)
+		}
+	}
+
+	// 2. Step down and back up.
+	version, err := db.MigrateDown(ctx, 2)
+	if err != nil {
+		t.Fatalf("Error migrating down: %v.\n", err)
+	}
+	if version != latest-2 {
+		t.Errorf("Error incorrect version: Have %v, want %v.\n", version, latest-2)
+	}
+
+	version, err = db.MigrateUp(ctx, 1)
+	if err != nil {
+		t.Fatalf("Error migrating up: %v.\n", err)
+	}
+	if version != latest-1 {
+		t.Errorf("Error incorrect version: Have %v, want %v.\n", version, latest-1)
+	}
+
+	// 3. Down to an empty schema and back.
+	if version, err = db.MigrateDown(ctx, latest+1); err != nil || version != 0 {
+		t.Errorf("Error migrating down: Have version %v and error %v, want version 0.\n", version, err)
+	}
+	if version, err = db.MigrateUp(ctx, 0); err != nil || version != latest {
+		t.Errorf("Error migrating up: Have version %v and error %v, want version %v.\n", version, err, latest)
+	}
+
+	// 4. Negative steps.
+	if _, err = db.MigrateDown(ctx, -1); !errors.Is(err, ErrMigrationSteps) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrMigrationSteps)
+	}
+}
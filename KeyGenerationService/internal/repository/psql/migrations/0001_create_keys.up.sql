@@ -0,0 +1,13 @@
+-- Tables are only created if they don't exist yet, so a database set up before migrations were introduced can be
+-- adopted. Such tables may lack a primary key, so the unique indexes give the ON CONFLICT clauses the constraint
+-- they need.
+CREATE TABLE IF NOT EXISTS keys (
+    values TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS used_keys (
+    values TEXT PRIMARY KEY
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS keys_values_idx ON keys (values);
+CREATE UNIQUE INDEX IF NOT EXISTS used_keys_values_idx ON used_keys (values);
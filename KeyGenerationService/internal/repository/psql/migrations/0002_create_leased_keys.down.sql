@@ -0,0 +1 @@
+DROP TABLE leased_keys;
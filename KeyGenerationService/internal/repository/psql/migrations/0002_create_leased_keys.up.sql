@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS leased_keys (
+    values     TEXT PRIMARY KEY,
+    lease_id   TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS leased_keys_lease_id_idx ON leased_keys (lease_id);
+CREATE INDEX IF NOT EXISTS leased_keys_expires_at_idx ON leased_keys (expires_at);
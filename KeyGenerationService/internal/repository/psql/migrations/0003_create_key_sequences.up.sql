@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS key_sequences (
+    length INTEGER PRIMARY KEY,
+    next   BIGINT NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX keys_length_idx;
+
+ALTER TABLE leased_keys DROP COLUMN length;
+ALTER TABLE used_keys DROP COLUMN length;
+ALTER TABLE keys DROP COLUMN length;
@@ -0,0 +1,6 @@
+ALTER TABLE keys ADD COLUMN IF NOT EXISTS length INTEGER GENERATED ALWAYS AS (char_length(values)) STORED;
+ALTER TABLE used_keys ADD COLUMN IF NOT EXISTS length INTEGER GENERATED ALWAYS AS (char_length(values)) STORED;
+ALTER TABLE leased_keys ADD COLUMN IF NOT EXISTS length INTEGER GENERATED ALWAYS AS (char_length(values)) STORED;
+
+-- Claims pick the shortest keys first.
+CREATE INDEX IF NOT EXISTS keys_length_idx ON keys (length);
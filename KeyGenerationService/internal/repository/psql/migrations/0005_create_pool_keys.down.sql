@@ -0,0 +1 @@
+DROP TABLE pool_keys;
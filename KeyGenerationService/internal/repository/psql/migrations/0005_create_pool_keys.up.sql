@@ -0,0 +1,14 @@
+-- Single-table layout used by SingleTableDB: every key stays in 'pool_keys', its state says whether it's unused,
+-- leased or used.
+CREATE TABLE IF NOT EXISTS pool_keys (
+    values     TEXT PRIMARY KEY,
+    length     INTEGER GENERATED ALWAYS AS (char_length(values)) STORED,
+    state      TEXT NOT NULL DEFAULT 'unused' CHECK (state IN ('unused', 'leased', 'used')),
+    lease_id   TEXT,
+    expires_at TIMESTAMPTZ
+);
+
+-- Claims only ever scan unused keys, shortest first.
+CREATE INDEX IF NOT EXISTS pool_keys_unused_idx ON pool_keys (length) WHERE state = 'unused';
+CREATE INDEX IF NOT EXISTS pool_keys_lease_id_idx ON pool_keys (lease_id) WHERE state = 'leased';
+CREATE INDEX IF NOT EXISTS pool_keys_expires_at_idx ON pool_keys (expires_at) WHERE state = 'leased';
@@ -6,19 +6,45 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 // DB used for Key Generation Service.
+// Unused keys live in 'keys', keys handed out under a lease in 'leased_keys' and used keys in 'used_keys'.
+// 'key_sequences' holds the next unreserved key space index per key length.
+// Each of the key tables has a 'length' column generated from 'values', so keys of different key lengths can coexist.
 type DB struct {
 	db *sql.DB
 }
 
-// New creates a new instance of DB.
+// New creates a new instance of DB connected to a local database without TLS.
 func New(user, password, database string) (*DB, error) {
-	db, err := sql.Open("postgres", fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable", user, password, database))
+	return Open(context.Background(), Config{User: user, Password: password, Database: database, SSLMode: "disable"})
+}
+
+// Open creates a new instance of DB from cfg, and pings the database before returning it.
+func Open(ctx context.Context, cfg Config) (*DB, error) {
+	dsn, err := cfg.connString()
 	if err != nil {
-		return nil, repository.ErrDatabaseError
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	// Zero would disable idle connections rather than keep the default.
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%w: %v", dbError(ctx), err)
 	}
 
 	return &DB{db: db}, nil
@@ -31,103 +57,424 @@ func (d *DB) KeyExist(ctx context.Context, key string) (bool, error) {
 
 	// Check key existence in keys.
 	query := "SELECT values FROM keys WHERE values=$1"
-	row := d.db.QueryRow(query, key)
+	row := d.db.QueryRowContext(ctx, query, key)
 
 	err := row.Scan(&value)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
-			return false, repository.ErrDatabaseError
+			return false, dbError(ctx)
 		}
 		inKeys = false
 	}
 
 	// Check key existence in used_keys.
 	query = "SELECT values FROM used_keys WHERE values=$1"
-	row = d.db.QueryRow(query, key)
+	row = d.db.QueryRowContext(ctx, query, key)
 
 	err = row.Scan(&value)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
-			return false, repository.ErrDatabaseError
+			return false, dbError(ctx)
 		}
 		inUsedKeys = false
 	}
 
-	if inKeys || inUsedKeys {
+	// Check key existence in leased_keys.
+	inLeasedKeys := true
+	query = "SELECT values FROM leased_keys WHERE values=$1"
+	row = d.db.QueryRowContext(ctx, query, key)
+
+	err = row.Scan(&value)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, dbError(ctx)
+		}
+		inLeasedKeys = false
+	}
+
+	if inKeys || inUsedKeys || inLeasedKeys {
 		return true, nil
 	} else {
 		return false, repository.ErrKeyNotFound
 	}
 }
 
-// WriteKey stores the given key to DB.
+// WriteKey stores the given key to DB, unless it's unused, leased or used already.
+// It holds the same key lock as ReserveKey, so a concurrent reservation can't slip in between its checks and insert.
 func (d *DB) WriteKey(ctx context.Context, key string) error {
-	query := "INSERT INTO keys(values) VALUES($1)"
-	_, err := d.db.Exec(query, key)
+	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return repository.ErrDatabaseError
+		return dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockKey(ctx, tx, key); err != nil {
+		return err
 	}
 
+	query := `INSERT INTO keys(values) SELECT $1::text
+	WHERE NOT EXISTS (SELECT 1 FROM leased_keys WHERE values=$1) AND NOT EXISTS (SELECT 1 FROM used_keys WHERE values=$1)
+	ON CONFLICT (values) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, key); err != nil {
+		return dbError(ctx)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx)
+	}
 	return nil
 }
 
-// GetKeys fetches an array of keys.
+// ReserveKey moves the given key from keys to used_keys, or inserts it into used_keys if it isn't stored yet.
+// Deleting the key from keys first locks its row, so concurrent claims skip it, and a claim that locked it first is
+// waited for. The key lock serializes it with WriteKey, which would otherwise store the key as unused again.
+func (d *DB) ReserveKey(ctx context.Context, key string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockKey(ctx, tx, key); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keys WHERE values=$1", key); err != nil {
+		return dbError(ctx)
+	}
+
+	query := `INSERT INTO used_keys(values) SELECT $1::text
+	WHERE NOT EXISTS (SELECT 1 FROM leased_keys WHERE values=$1)
+	ON CONFLICT (values) DO NOTHING`
+	res, err := tx.ExecContext(ctx, query, key)
+	if err != nil {
+		return dbError(ctx)
+	}
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		return dbError(ctx)
+	}
+	if reserved == 0 {
+		return repository.ErrKeyUsed
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx)
+	}
+	return nil
+}
+
+// GetKeys fetches an array of keys, shortest keys first.
 // The fetched keys are considered used and will be moved to used_keys for further usage.
+// Keys are claimed in a single statement within a transaction, rows locked by concurrent callers are skipped,
+// so every key is handed to exactly one caller and a failed claim leaves both tables untouched.
 func (d *DB) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	return d.GetKeysAtLeast(ctx, 0, requiredKeys)
+}
+
+// GetKeysAtLeast fetches an array of keys at least minLength long, shortest keys first.
+func (d *DB) GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error) {
 	// Cannot have negative or zero requiredKeys.
 	if requiredKeys <= 0 {
 		return []string{}, repository.ErrKeyOOR
 	}
 
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	query := `WITH claimed AS (
+		DELETE FROM keys
+		WHERE values IN (
+			SELECT values FROM keys WHERE length >= $2 ORDER BY length LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING values
+	)
+	INSERT INTO used_keys(values) SELECT values FROM claimed RETURNING values`
+	result, err := queryKeys(ctx, tx, query, requiredKeys, minLength)
+	if err != nil {
+		return nil, err
+	}
+
 	// Cannot have requiredKeys greater than what we have in 'keys'.
 	// This shouldn't happen since we always assume that we have enough keys in pool waiting.
-	rows, err := d.db.Query("SELECT COUNT(*) FROM keys;")
-	if err != nil {
-		return nil, repository.ErrDatabaseError
+	if len(result) < requiredKeys {
+		return []string{}, repository.ErrKeyOOR
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(ctx)
+	}
+
+	return result, nil
+}
+
+// KeyCount returns the amount of unused keys in DB.
+func (d *DB) KeyCount(ctx context.Context) (int, error) {
 	var count int
+	row := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM keys")
+	if err := row.Scan(&count); err != nil {
+		return 0, dbError(ctx)
+	}
+
+	return count, nil
+}
+
+// Stats counts the keys in keys, leased_keys and used_keys, in total and per key length.
+func (d *DB) Stats(ctx context.Context) (repository.Stats, error) {
+	query := `SELECT 'unused', length, COUNT(*) FROM keys GROUP BY length
+	UNION ALL SELECT 'leased', length, COUNT(*) FROM leased_keys GROUP BY length
+	UNION ALL SELECT 'used', length, COUNT(*) FROM used_keys GROUP BY length`
+	return queryStats(ctx, d.db, query)
+}
+
+// queryStats runs a query returning rows of state, key length and count, and sums them up.
+// Both table layouts report the states 'unused', 'leased' and 'used'.
+func queryStats(ctx context.Context, db *sql.DB, query string) (repository.Stats, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return repository.Stats{}, dbError(ctx)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := repository.Stats{ByLength: make(map[int]repository.Counts)}
 	for rows.Next() {
-		if err := rows.Scan(&count); err != nil {
-			return nil, repository.ErrDatabaseError
+		var state string
+		var length, count int
+		if err := rows.Scan(&state, &length, &count); err != nil {
+			return repository.Stats{}, dbError(ctx)
+		}
+
+		counts := stats.ByLength[length]
+		switch state {
+		case "unused":
+			counts.Unused += count
+			stats.Unused += count
+		case "leased":
+			counts.Leased += count
+			stats.Leased += count
+		case "used":
+			counts.Used += count
+			stats.Used += count
 		}
+		stats.ByLength[length] = counts
 	}
-	_ = rows.Close()
-	if requiredKeys > count {
-		return []string{}, repository.ErrKeyOOR
+	if err := rows.Err(); err != nil {
+		return repository.Stats{}, dbError(ctx)
 	}
 
-	// Create an array that stores all fetched keys.
-	result := make([]string, requiredKeys)
-	query := "SELECT values FROM keys FETCH FIRST $1 ROWS ONLY"
-	rows, err = d.db.Query(query, requiredKeys)
+	return stats, nil
+}
+
+// LeaseKeys moves requiredKeys keys from keys to leased_keys under a new lease that expires after ttl.
+func (d *DB) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	return d.LeaseKeysAtLeast(ctx, 0, requiredKeys, ttl)
+}
+
+// LeaseKeysAtLeast moves requiredKeys keys at least minLength long from keys to leased_keys under a new lease that
+// expires after ttl.
+func (d *DB) LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return repository.Lease{}, repository.ErrKeyOOR
+	}
+
+	id, err := repository.NewLeaseID()
+	if err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	query := `WITH claimed AS (
+		DELETE FROM keys
+		WHERE values IN (
+			SELECT values FROM keys WHERE length >= $4 ORDER BY length LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING values
+	)
+	INSERT INTO leased_keys(values, lease_id, expires_at) SELECT values, $2, $3 FROM claimed RETURNING values`
+	keys, err := queryKeys(ctx, tx, query, requiredKeys, id, expiresAt, minLength)
+	if err != nil {
+		return repository.Lease{}, err
+	}
+
+	// Same as GetKeys, a short batch means the pool ran dry.
+	if len(keys) < requiredKeys {
+		return repository.Lease{}, repository.ErrKeyOOR
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+
+	return repository.Lease{ID: id, Keys: keys, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmKeys moves the given leased keys to used_keys.
+func (d *DB) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	return d.settleKeys(ctx, leaseID, keys, false, "used_keys")
+}
+
+// ReleaseKeys moves the given leased keys back to keys, or every key left in the lease if keys is empty.
+func (d *DB) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	return d.settleKeys(ctx, leaseID, keys, len(keys) == 0, "keys")
+}
+
+// settleKeys moves the given keys, or every key left in the lease if all is set, out of a lease into table.
+// Either every key is moved or none is.
+func (d *DB) settleKeys(ctx context.Context, leaseID string, keys []string, all bool, table string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	var active bool
+	row := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM leased_keys WHERE lease_id=$1 AND expires_at > $2)", leaseID, now)
+	if err := row.Scan(&active); err != nil {
+		return dbError(ctx)
+	}
+	if !active {
+		return repository.ErrLeaseNotFound
+	}
+
+	// table is never user input, it's either 'keys' or 'used_keys'.
+	var settled []string
+	if all {
+		query := fmt.Sprintf(`WITH settled AS (
+			DELETE FROM leased_keys WHERE lease_id=$1 RETURNING values
+		)
+		INSERT INTO %s(values) SELECT values FROM settled RETURNING values`, table)
+		settled, err = queryKeys(ctx, tx, query, leaseID)
+	} else {
+		keys = dedupe(keys)
+		query := fmt.Sprintf(`WITH settled AS (
+			DELETE FROM leased_keys WHERE lease_id=$1 AND values = ANY($2) RETURNING values
+		)
+		INSERT INTO %s(values) SELECT values FROM settled RETURNING values`, table)
+		settled, err = queryKeys(ctx, tx, query, leaseID, pq.Array(keys))
+		if err == nil && len(settled) < len(keys) {
+			return repository.ErrKeyNotLeased
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx)
+	}
+	return nil
+}
+
+// ExpireLeases moves the keys of every expired lease back to keys.
+func (d *DB) ExpireLeases(ctx context.Context) (int, error) {
+	query := `WITH expired AS (
+		DELETE FROM leased_keys WHERE expires_at <= $1 RETURNING values
+	)
+	INSERT INTO keys(values) SELECT values FROM expired`
+	res, err := d.db.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+
+	expired, err := res.RowsAffected()
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+	return int(expired), nil
+}
+
+// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+// The upsert takes a row lock, so concurrent reservations, even from other KGS instances, never overlap.
+func (d *DB) ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error) {
+	if n < 0 {
+		return 0, repository.ErrKeyOOR
+	}
+
+	return reserveIndexes(ctx, d.db, keyLength, n)
+}
+
+// reserveIndexes reserves indexes in key_sequences, which both table layouts share.
+func reserveIndexes(ctx context.Context, db *sql.DB, keyLength int, n int) (uint64, error) {
+	query := `INSERT INTO key_sequences(length, next) VALUES ($1, $2)
+	ON CONFLICT (length) DO UPDATE SET next = key_sequences.next + EXCLUDED.next
+	RETURNING next - $2`
+	var start int64
+	row := db.QueryRowContext(ctx, query, keyLength, n)
+	if err := row.Scan(&start); err != nil {
+		return 0, dbError(ctx)
+	}
+
+	return uint64(start), nil
+}
+
+// dbError reports a failed query. If ctx was cancelled or timed out, the context error is wrapped as well,
+// so callers can tell an abandoned request from a broken database.
+func dbError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", repository.ErrDatabaseError, err)
+	}
+	return repository.ErrDatabaseError
+}
+
+// lockKey takes a lock on key that is held until tx ends. A key that doesn't exist yet has no row to lock, so an
+// advisory lock on its hash is used instead. Hash collisions only serialize unrelated keys.
+func lockKey(ctx context.Context, tx *sql.Tx, key string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+		return dbError(ctx)
+	}
+	return nil
+}
+
+// queryKeys runs a query within tx that returns a single column of keys.
+func queryKeys(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, repository.ErrDatabaseError
+		return nil, dbError(ctx)
 	}
+	defer func() { _ = rows.Close() }()
 
-	i := 0
+	var keys []string
 	for rows.Next() {
 		var key string
-		err := rows.Scan(&key)
-		if err != nil {
-			return nil, repository.ErrDatabaseError
+		if err := rows.Scan(&key); err != nil {
+			return nil, dbError(ctx)
 		}
-		result[i] = key
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx)
+	}
+	return keys, nil
+}
 
-		_, err = d.db.Exec("DELETE FROM keys WHERE values=$1", key)
-		if err != nil {
-			return nil, repository.ErrDatabaseError
+// dedupe returns keys without duplicates, keeping their order.
+func dedupe(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
 		}
-		_, err = d.db.Exec("INSERT INTO used_keys(values) VALUES($1)", key)
-		if err != nil {
-			return nil, repository.ErrDatabaseError
-		}
-		i++
+		seen[key] = struct{}{}
+		result = append(result, key)
 	}
-
-	_ = rows.Close()
-
-	return result, nil
+	return result
 }
 
 func (d *DB) CleanUp() {
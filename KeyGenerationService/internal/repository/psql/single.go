@@ -0,0 +1,255 @@
+package psql
+
+import (
+	"KeyGenerationService/internal/repository"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SingleTableDB is the single table layout of the Key Generation Service database.
+// Every key stays in 'pool_keys' for its whole life, and its state column marks it as 'unused', 'leased' or 'used'.
+// Claims only scan unused keys through a partial index, so used keys piling up don't slow them down.
+// It shares the connection pool, the migrations and 'key_sequences' with DB.
+type SingleTableDB struct {
+	db *sql.DB
+}
+
+// SingleTable returns the single table layout on the connection pool of d.
+func (d *DB) SingleTable() *SingleTableDB {
+	return &SingleTableDB{db: d.db}
+}
+
+// KeyExist checks whether a key exist within SingleTableDB, whatever its state.
+func (s *SingleTableDB) KeyExist(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	row := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pool_keys WHERE values=$1)", key)
+	if err := row.Scan(&exists); err != nil {
+		return false, dbError(ctx)
+	}
+
+	if !exists {
+		return false, repository.ErrKeyNotFound
+	}
+	return true, nil
+}
+
+// WriteKey stores the given key as unused, unless it's stored already.
+func (s *SingleTableDB) WriteKey(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, "INSERT INTO pool_keys(values) VALUES($1) ON CONFLICT (values) DO NOTHING", key); err != nil {
+		return dbError(ctx)
+	}
+
+	return nil
+}
+
+// ReserveKey marks the given key as used if it's unused, or stores it as used if it isn't stored yet.
+// The upsert is a single statement, so it waits for a concurrent claim of the key and then sees it as used.
+func (s *SingleTableDB) ReserveKey(ctx context.Context, key string) error {
+	query := `INSERT INTO pool_keys(values, state) VALUES ($1, 'used')
+	ON CONFLICT (values) DO UPDATE SET state = 'used' WHERE pool_keys.state = 'unused'`
+	res, err := s.db.ExecContext(ctx, query, key)
+	if err != nil {
+		return dbError(ctx)
+	}
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		return dbError(ctx)
+	}
+	if reserved == 0 {
+		return repository.ErrKeyUsed
+	}
+
+	return nil
+}
+
+// GetKeys marks requiredKeys unused keys as used and returns them, shortest keys first.
+func (s *SingleTableDB) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	return s.GetKeysAtLeast(ctx, 0, requiredKeys)
+}
+
+// GetKeysAtLeast marks requiredKeys unused keys at least minLength long as used and returns them, shortest keys first.
+// Like DB, rows locked by concurrent callers are skipped and a short batch leaves the table untouched.
+func (s *SingleTableDB) GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return []string{}, repository.ErrKeyOOR
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE pool_keys SET state = 'used'
+	WHERE values IN (
+		SELECT values FROM pool_keys WHERE state = 'unused' AND length >= $2 ORDER BY length LIMIT $1 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING values`
+	result, err := queryKeys(ctx, tx, query, requiredKeys, minLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) < requiredKeys {
+		return []string{}, repository.ErrKeyOOR
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(ctx)
+	}
+
+	return result, nil
+}
+
+// KeyCount returns the amount of unused keys.
+func (s *SingleTableDB) KeyCount(ctx context.Context) (int, error) {
+	var count int
+	row := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pool_keys WHERE state = 'unused'")
+	if err := row.Scan(&count); err != nil {
+		return 0, dbError(ctx)
+	}
+
+	return count, nil
+}
+
+// Stats counts the keys of every state, in total and per key length.
+func (s *SingleTableDB) Stats(ctx context.Context) (repository.Stats, error) {
+	return queryStats(ctx, s.db, "SELECT state, length, COUNT(*) FROM pool_keys GROUP BY state, length")
+}
+
+// LeaseKeys marks requiredKeys unused keys as leased under a new lease that expires after ttl.
+func (s *SingleTableDB) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	return s.LeaseKeysAtLeast(ctx, 0, requiredKeys, ttl)
+}
+
+// LeaseKeysAtLeast marks requiredKeys unused keys at least minLength long as leased under a new lease that expires
+// after ttl.
+func (s *SingleTableDB) LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return repository.Lease{}, repository.ErrKeyOOR
+	}
+
+	id, err := repository.NewLeaseID()
+	if err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	query := `UPDATE pool_keys SET state = 'leased', lease_id = $2, expires_at = $3
+	WHERE values IN (
+		SELECT values FROM pool_keys WHERE state = 'unused' AND length >= $4 ORDER BY length LIMIT $1 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING values`
+	keys, err := queryKeys(ctx, tx, query, requiredKeys, id, expiresAt, minLength)
+	if err != nil {
+		return repository.Lease{}, err
+	}
+
+	if len(keys) < requiredKeys {
+		return repository.Lease{}, repository.ErrKeyOOR
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+
+	return repository.Lease{ID: id, Keys: keys, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmKeys marks the given leased keys as used.
+func (s *SingleTableDB) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	return s.settleKeys(ctx, leaseID, keys, false, "used")
+}
+
+// ReleaseKeys marks the given leased keys as unused again, or every key left in the lease if keys is empty.
+func (s *SingleTableDB) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	return s.settleKeys(ctx, leaseID, keys, len(keys) == 0, "unused")
+}
+
+// settleKeys moves the given keys, or every key left in the lease if all is set, out of a lease into state.
+// Either every key is moved or none is.
+func (s *SingleTableDB) settleKeys(ctx context.Context, leaseID string, keys []string, all bool, state string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx)
+	}
+	// Rollback is a no-op once the transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	var active bool
+	query := "SELECT EXISTS(SELECT 1 FROM pool_keys WHERE state = 'leased' AND lease_id=$1 AND expires_at > $2)"
+	row := tx.QueryRowContext(ctx, query, leaseID, time.Now())
+	if err := row.Scan(&active); err != nil {
+		return dbError(ctx)
+	}
+	if !active {
+		return repository.ErrLeaseNotFound
+	}
+
+	if all {
+		query := `UPDATE pool_keys SET state = $2, lease_id = NULL, expires_at = NULL
+		WHERE state = 'leased' AND lease_id=$1`
+		if _, err := tx.ExecContext(ctx, query, leaseID, state); err != nil {
+			return dbError(ctx)
+		}
+	} else {
+		keys = dedupe(keys)
+		query := `UPDATE pool_keys SET state = $3, lease_id = NULL, expires_at = NULL
+		WHERE state = 'leased' AND lease_id=$1 AND values = ANY($2)
+		RETURNING values`
+		settled, err := queryKeys(ctx, tx, query, leaseID, pq.Array(keys), state)
+		if err != nil {
+			return err
+		}
+		if len(settled) < len(keys) {
+			return repository.ErrKeyNotLeased
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx)
+	}
+	return nil
+}
+
+// ExpireLeases marks the keys of every expired lease as unused again.
+func (s *SingleTableDB) ExpireLeases(ctx context.Context) (int, error) {
+	query := `UPDATE pool_keys SET state = 'unused', lease_id = NULL, expires_at = NULL
+	WHERE state = 'leased' AND expires_at <= $1`
+	res, err := s.db.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+
+	expired, err := res.RowsAffected()
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+	return int(expired), nil
+}
+
+// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+func (s *SingleTableDB) ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error) {
+	if n < 0 {
+		return 0, repository.ErrKeyOOR
+	}
+
+	return reserveIndexes(ctx, s.db, keyLength, n)
+}
+
+func (s *SingleTableDB) CleanUp() {
+	_, _ = s.db.Exec("DELETE FROM pool_keys")
+}
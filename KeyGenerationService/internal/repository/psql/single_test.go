@@ -0,0 +1,55 @@
+package psql
+
+import (
+	"KeyGenerationService/internal/repository"
+	"KeyGenerationService/internal/repository/repositorytest"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSingleTableDB_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.KGSDatabase {
+		return newTestDB(t).SingleTable()
+	})
+}
+
+// The benchmarks compare both table layouts claiming keys from a pool that already handed out most of its keys.
+func BenchmarkGetKeys(b *testing.B) {
+	db := newTestDB(b)
+
+	layouts := []struct {
+		name  string
+		db    repository.KGSDatabase
+		clean func()
+	}{
+		{name: "two tables", db: db, clean: func() { _, _ = db.db.Exec("TRUNCATE keys, used_keys") }},
+		{name: "single table", db: db.SingleTable(), clean: db.SingleTable().CleanUp},
+	}
+
+	const requiredKeys = 10
+	for _, layout := range layouts {
+		b.Run(layout.name, func(b *testing.B) {
+			ctx := context.Background()
+			layout.clean()
+			defer layout.clean()
+
+			pool := 10000 + b.N*requiredKeys
+			for i := 0; i < pool; i++ {
+				if err := layout.db.WriteKey(ctx, fmt.Sprintf("%08d", i)); err != nil {
+					b.Fatalf("Error writing key to database: %v.\n", err)
+				}
+			}
+			if _, err := layout.db.GetKeys(ctx, pool-b.N*requiredKeys); err != nil {
+				b.Fatalf("Error getting keys: %v.\n", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := layout.db.GetKeys(ctx, requiredKeys); err != nil {
+					b.Fatalf("Error getting keys: %v.\n", err)
+				}
+			}
+		})
+	}
+}
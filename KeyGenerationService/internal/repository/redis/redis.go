@@ -0,0 +1,273 @@
+package redis
+
+import (
+	"KeyGenerationService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Error replies of the scripts, mapped back to repository errors.
+const (
+	errLeaseNotFound = "KGS_LEASE_NOT_FOUND"
+	errKeyNotLeased  = "KGS_KEY_NOT_LEASED"
+	errKeyUsed       = "KGS_KEY_USED"
+)
+
+// DB keeps the Key Generation Service key pool in Redis. Every key lives in exactly one set per state and key length:
+// '<prefix>unused:<length>', '<prefix>leased:<length>' and '<prefix>used:<length>'.
+// '<prefix>lengths' holds every key length written so far, '<prefix>lease:<id>' the keys left in a lease,
+// '<prefix>leases' the expiry of every lease and '<prefix>sequences' the next unreserved key space index per length.
+// Claims and lease changes run as Lua scripts, so each of them is a single atomic step.
+type DB struct {
+	client *goredis.Client
+	prefix string
+}
+
+// Config describes how to connect to Redis.
+type Config struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// Prefix is put in front of every Redis key, so several key pools can share a Redis database.
+	Prefix string
+}
+
+// Open creates a new instance of DB from cfg, and pings Redis before returning it.
+func Open(ctx context.Context, cfg Config) (*DB, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("%w: %v", dbError(ctx), err)
+	}
+
+	return &DB{client: client, prefix: cfg.Prefix}, nil
+}
+
+// Close closes the connections to Redis.
+func (d *DB) Close() error {
+	return d.client.Close()
+}
+
+// KeyExist checks whether a key exist within DB, whatever its state.
+func (d *DB) KeyExist(ctx context.Context, key string) (bool, error) {
+	length := len(key)
+	cmds, err := d.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, state := range []string{"unused", "leased", "used"} {
+			pipe.SIsMember(ctx, d.set(state, length), key)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, dbError(ctx)
+	}
+
+	for _, cmd := range cmds {
+		if cmd.(*goredis.BoolCmd).Val() {
+			return true, nil
+		}
+	}
+	return false, repository.ErrKeyNotFound
+}
+
+// WriteKey stores the given key as unused, unless it's leased or used already.
+func (d *DB) WriteKey(ctx context.Context, key string) error {
+	if err := writeScript.Run(ctx, d.client, nil, d.prefix, key, "unused").Err(); err != nil {
+		// A leased or used key is left as is.
+		if strings.Contains(err.Error(), errKeyUsed) {
+			return nil
+		}
+		return dbError(ctx)
+	}
+
+	return nil
+}
+
+// ReserveKey moves the given key from the unused to the used sets, or stores it as used if it isn't stored yet.
+func (d *DB) ReserveKey(ctx context.Context, key string) error {
+	if err := writeScript.Run(ctx, d.client, nil, d.prefix, key, "used").Err(); err != nil {
+		if strings.Contains(err.Error(), errKeyUsed) {
+			return repository.ErrKeyUsed
+		}
+		return dbError(ctx)
+	}
+
+	return nil
+}
+
+// GetKeys fetches an array of keys, shortest keys first. The fetched keys are moved to the used sets.
+func (d *DB) GetKeys(ctx context.Context, requiredKeys int) ([]string, error) {
+	return d.GetKeysAtLeast(ctx, 0, requiredKeys)
+}
+
+// GetKeysAtLeast fetches an array of keys at least minLength long, shortest keys first.
+func (d *DB) GetKeysAtLeast(ctx context.Context, minLength int, requiredKeys int) ([]string, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return []string{}, repository.ErrKeyOOR
+	}
+
+	keys, err := claimScript.Run(ctx, d.client, nil, d.prefix, minLength, requiredKeys, "used").StringSlice()
+	if err != nil {
+		// Cannot have requiredKeys greater than what we have in the unused sets.
+		if errors.Is(err, goredis.Nil) {
+			return []string{}, repository.ErrKeyOOR
+		}
+		return nil, dbError(ctx)
+	}
+
+	return keys, nil
+}
+
+// KeyCount returns the amount of unused keys.
+func (d *DB) KeyCount(ctx context.Context) (int, error) {
+	stats, err := d.Stats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Unused, nil
+}
+
+// Stats counts the unused, leased and used keys, in total and per key length.
+func (d *DB) Stats(ctx context.Context) (repository.Stats, error) {
+	counts, err := statsScript.Run(ctx, d.client, nil, d.prefix).Int64Slice()
+	if err != nil {
+		return repository.Stats{}, dbError(ctx)
+	}
+
+	stats := repository.Stats{ByLength: make(map[int]repository.Counts)}
+	for i := 0; i+3 < len(counts); i += 4 {
+		c := repository.Counts{Unused: int(counts[i+1]), Leased: int(counts[i+2]), Used: int(counts[i+3])}
+		if c == (repository.Counts{}) {
+			continue
+		}
+		stats.ByLength[int(counts[i])] = c
+		stats.Unused += c.Unused
+		stats.Leased += c.Leased
+		stats.Used += c.Used
+	}
+
+	return stats, nil
+}
+
+// LeaseKeys moves requiredKeys unused keys into a new lease that expires after ttl.
+func (d *DB) LeaseKeys(ctx context.Context, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	return d.LeaseKeysAtLeast(ctx, 0, requiredKeys, ttl)
+}
+
+// LeaseKeysAtLeast moves requiredKeys unused keys at least minLength long into a new lease that expires after ttl.
+func (d *DB) LeaseKeysAtLeast(ctx context.Context, minLength int, requiredKeys int, ttl time.Duration) (repository.Lease, error) {
+	// Cannot have negative or zero requiredKeys.
+	if requiredKeys <= 0 {
+		return repository.Lease{}, repository.ErrKeyOOR
+	}
+
+	id, err := repository.NewLeaseID()
+	if err != nil {
+		return repository.Lease{}, dbError(ctx)
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	keys, err := claimScript.Run(ctx, d.client, nil, d.prefix, minLength, requiredKeys, "leased", id, expiresAt.UnixMilli()).StringSlice()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return repository.Lease{}, repository.ErrKeyOOR
+		}
+		return repository.Lease{}, dbError(ctx)
+	}
+
+	return repository.Lease{ID: id, Keys: keys, ExpiresAt: expiresAt}, nil
+}
+
+// ConfirmKeys moves the given leased keys to the used sets.
+func (d *DB) ConfirmKeys(ctx context.Context, leaseID string, keys []string) error {
+	return d.settleKeys(ctx, leaseID, keys, false, "used")
+}
+
+// ReleaseKeys moves the given leased keys back to the unused sets, or every key left in the lease if keys is empty.
+func (d *DB) ReleaseKeys(ctx context.Context, leaseID string, keys []string) error {
+	return d.settleKeys(ctx, leaseID, keys, len(keys) == 0, "unused")
+}
+
+// settleKeys moves the given keys, or every key left in the lease if all is set, out of a lease into the sets of
+// state. Either every key is moved or none is.
+func (d *DB) settleKeys(ctx context.Context, leaseID string, keys []string, all bool, state string) error {
+	allFlag := "0"
+	if all {
+		allFlag = "1"
+	}
+	args := []any{d.prefix, leaseID, time.Now().UnixMilli(), state, allFlag}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	err := settleScript.Run(ctx, d.client, nil, args...).Err()
+	switch {
+	case err == nil:
+		return nil
+	case strings.Contains(err.Error(), errLeaseNotFound):
+		return repository.ErrLeaseNotFound
+	case strings.Contains(err.Error(), errKeyNotLeased):
+		return repository.ErrKeyNotLeased
+	default:
+		return dbError(ctx)
+	}
+}
+
+// ExpireLeases moves the keys of every expired lease back to the unused sets.
+func (d *DB) ExpireLeases(ctx context.Context) (int, error) {
+	expired, err := expireScript.Run(ctx, d.client, nil, d.prefix, time.Now().UnixMilli()).Int()
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+	return expired, nil
+}
+
+// ReserveIndexes reserves the next n indexes of the key space of the given key length and returns the first one.
+func (d *DB) ReserveIndexes(ctx context.Context, keyLength int, n int) (uint64, error) {
+	if n < 0 {
+		return 0, repository.ErrKeyOOR
+	}
+
+	// HINCRBY is atomic, so concurrent reservations, even from other KGS instances, never overlap.
+	next, err := d.client.HIncrBy(ctx, d.prefix+"sequences", strconv.Itoa(keyLength), int64(n)).Result()
+	if err != nil {
+		return 0, dbError(ctx)
+	}
+
+	return uint64(next) - uint64(n), nil
+}
+
+// set returns the name of the set holding keys of the given state and length.
+func (d *DB) set(state string, length int) string {
+	return d.prefix + state + ":" + strconv.Itoa(length)
+}
+
+// dbError reports a failed command. If ctx was cancelled or timed out, the context error is wrapped as well.
+func dbError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", repository.ErrDatabaseError, err)
+	}
+	return repository.ErrDatabaseError
+}
+
+// CleanUp deletes every key of the key pool.
+func (d *DB) CleanUp() {
+	ctx := context.Background()
+	iter := d.client.Scan(ctx, 0, d.prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		_ = d.client.Del(ctx, iter.Val()).Err()
+	}
+}
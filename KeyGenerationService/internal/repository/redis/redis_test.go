@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"KeyGenerationService/internal/repository"
+	"KeyGenerationService/internal/repository/repositorytest"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// openTestDB opens a DB on a fresh miniredis, or on the Redis server at KGS_TEST_REDIS_ADDR if it's set.
+// Every test gets its own key prefix, which is cleaned up afterwards.
+func openTestDB(t *testing.T) (*DB, string) {
+	addr := os.Getenv("KGS_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = miniredis.RunT(t).Addr()
+	}
+
+	db, err := Open(context.Background(), Config{Addr: addr, Prefix: "kgs-test:" + t.Name() + ":"})
+	if err != nil {
+		t.Fatalf("Error opening database: %v.\n", err)
+	}
+	t.Cleanup(func() {
+		db.CleanUp()
+		_ = db.Close()
+	})
+	return db, addr
+}
+
+func TestOpen(t *testing.T) {
+	db, _ := openTestDB(t)
+	if db == nil {
+		t.Errorf("Error shouldn't return a nil DB instance.\n")
+	}
+
+	// Redis isn't reachable.
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	if _, err := Open(context.Background(), Config{Addr: addr}); !errors.Is(err, repository.ErrDatabaseError) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrDatabaseError)
+	}
+}
+
+func TestDB_Prefix(t *testing.T) {
+	db, addr := openTestDB(t)
+	ctx := context.Background()
+
+	other, err := Open(ctx, Config{Addr: addr, Prefix: db.prefix + "other:"})
+	if err != nil {
+		t.Fatalf("Error opening database: %v.\n", err)
+	}
+	defer func() { _ = other.Close() }()
+
+	// Key pools with different prefixes don't see each other's keys.
+	_ = db.WriteKey(ctx, "key1")
+	if count, _ := other.KeyCount(ctx); count != 0 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 0)
+	}
+
+	db.CleanUp()
+	if keys := db.client.Keys(ctx, db.prefix+"*").Val(); len(keys) != 0 {
+		t.Errorf("Error clean up left keys behind: %v.\n", keys)
+	}
+}
+
+func TestDB_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.KGSDatabase {
+		db, _ := openTestDB(t)
+		return db
+	})
+}
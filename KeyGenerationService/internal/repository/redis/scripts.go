@@ -0,0 +1,136 @@
+package redis
+
+import goredis "github.com/redis/go-redis/v9"
+
+// Every script gets the key prefix as ARGV[1] and builds the names of the sets it touches from it.
+// Key names aren't declared in KEYS, so the scripts need a single Redis node rather than a cluster.
+
+// writeScript stores key ARGV[2] in the sets of state ARGV[3], 'unused' or 'used', moving it out of the unused sets.
+// It fails without changing anything if the key is leased or used already.
+var writeScript = goredis.NewScript(`
+local prefix, key, dst = ARGV[1], ARGV[2], ARGV[3]
+local length = string.len(key)
+
+if redis.call('SISMEMBER', prefix .. 'leased:' .. length, key) == 1 or
+	redis.call('SISMEMBER', prefix .. 'used:' .. length, key) == 1 then
+	return redis.error_reply('` + errKeyUsed + `')
+end
+
+redis.call('SADD', prefix .. 'lengths', length)
+redis.call('SREM', prefix .. 'unused:' .. length, key)
+redis.call('SADD', prefix .. dst .. ':' .. length, key)
+return 1
+`)
+
+// claimScript moves ARGV[3] unused keys at least ARGV[2] long into the sets of state ARGV[4], shortest keys first.
+// When leasing, ARGV[5] is the lease ID and ARGV[6] its expiry in Unix milliseconds.
+// It returns nil without claiming anything if there aren't enough keys.
+var claimScript = goredis.NewScript(`
+local prefix, minLength, n, dst = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3]), ARGV[4]
+
+local lengths = {}
+for _, length in ipairs(redis.call('SMEMBERS', prefix .. 'lengths')) do
+	length = tonumber(length)
+	if length >= minLength then
+		table.insert(lengths, length)
+	end
+end
+table.sort(lengths)
+
+local available = 0
+for _, length in ipairs(lengths) do
+	available = available + redis.call('SCARD', prefix .. 'unused:' .. length)
+end
+if available < n then
+	return false
+end
+
+local result = {}
+for _, length in ipairs(lengths) do
+	if #result == n then
+		break
+	end
+	for _, key in ipairs(redis.call('SPOP', prefix .. 'unused:' .. length, n - #result)) do
+		table.insert(result, key)
+		redis.call('SADD', prefix .. dst .. ':' .. length, key)
+		if dst == 'leased' then
+			redis.call('SADD', prefix .. 'lease:' .. ARGV[5], key)
+		end
+	end
+end
+if dst == 'leased' then
+	redis.call('ZADD', prefix .. 'leases', ARGV[6], ARGV[5])
+end
+return result
+`)
+
+// settleScript moves keys out of lease ARGV[2] into the sets of state ARGV[4], if the lease hasn't expired by
+// ARGV[3] in Unix milliseconds. With ARGV[5] set to '1' every key left in the lease is moved, otherwise the keys
+// given from ARGV[6] on, and either all of them or none are moved.
+var settleScript = goredis.NewScript(`
+local prefix, id, now, dst, all = ARGV[1], ARGV[2], tonumber(ARGV[3]), ARGV[4], ARGV[5]
+local leaseKey = prefix .. 'lease:' .. id
+
+local expiresAt = redis.call('ZSCORE', prefix .. 'leases', id)
+if not expiresAt or tonumber(expiresAt) <= now then
+	return redis.error_reply('` + errLeaseNotFound + `')
+end
+
+local keys = {}
+if all == '1' then
+	keys = redis.call('SMEMBERS', leaseKey)
+else
+	for i = 6, #ARGV do
+		if redis.call('SISMEMBER', leaseKey, ARGV[i]) == 0 then
+			return redis.error_reply('` + errKeyNotLeased + `')
+		end
+		table.insert(keys, ARGV[i])
+	end
+end
+
+for _, key in ipairs(keys) do
+	-- A key given twice is already settled.
+	if redis.call('SREM', leaseKey, key) == 1 then
+		redis.call('SREM', prefix .. 'leased:' .. string.len(key), key)
+		redis.call('SADD', prefix .. dst .. ':' .. string.len(key), key)
+	end
+end
+if redis.call('SCARD', leaseKey) == 0 then
+	redis.call('ZREM', prefix .. 'leases', id)
+end
+return #keys
+`)
+
+// expireScript moves the keys of every lease that expired by ARGV[2] in Unix milliseconds back to the unused sets,
+// and returns how many keys were moved.
+var expireScript = goredis.NewScript(`
+local prefix, now = ARGV[1], ARGV[2]
+
+local expired = 0
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', prefix .. 'leases', '-inf', now)) do
+	local leaseKey = prefix .. 'lease:' .. id
+	for _, key in ipairs(redis.call('SMEMBERS', leaseKey)) do
+		redis.call('SREM', prefix .. 'leased:' .. string.len(key), key)
+		redis.call('SADD', prefix .. 'unused:' .. string.len(key), key)
+		expired = expired + 1
+	end
+	redis.call('DEL', leaseKey)
+	redis.call('ZREM', prefix .. 'leases', id)
+end
+return expired
+`)
+
+// statsScript returns the unused, leased and used key counts of every key length, as a flat list of
+// length, unused, leased and used.
+var statsScript = goredis.NewScript(`
+local prefix = ARGV[1]
+
+local result = {}
+for _, length in ipairs(redis.call('SMEMBERS', prefix .. 'lengths')) do
+	table.insert(result, tonumber(length))
+	table.insert(result, redis.call('SCARD', prefix .. 'unused:' .. length))
+	table.insert(result, redis.call('SCARD', prefix .. 'leased:' .. length))
+	table.insert(result, redis.call('SCARD', prefix .. 'used:' .. length))
+end
+return result
+`)
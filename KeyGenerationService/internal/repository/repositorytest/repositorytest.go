@@ -0,0 +1,506 @@
+// Package repositorytest is a conformance suite for repository.KGSDatabase implementations.
+// Every backend runs it from its own tests, so they all behave the same towards the controller.
+package repositorytest
+
+import (
+	"KeyGenerationService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewDB returns an empty database for a single subtest, cleaning it up with t.Cleanup if needed.
+type NewDB func(t *testing.T) repository.KGSDatabase
+
+// Run runs the conformance suite against the databases returned by newDB.
+func Run(t *testing.T, newDB NewDB) {
+	t.Run("KeyExist", func(t *testing.T) { testKeyExist(t, newDB(t)) })
+	t.Run("WriteKey", func(t *testing.T) { testWriteKey(t, newDB(t)) })
+	t.Run("GetKeys", func(t *testing.T) { testGetKeys(t, newDB(t)) })
+	t.Run("GetKeysAtLeast", func(t *testing.T) { testGetKeysAtLeast(t, newDB(t)) })
+	t.Run("Stats", func(t *testing.T) { testStats(t, newDB(t)) })
+	t.Run("Leases", func(t *testing.T) { testLeases(t, newDB(t)) })
+	t.Run("ExpireLeases", func(t *testing.T) { testExpireLeases(t, newDB(t)) })
+	t.Run("ReserveIndexes", func(t *testing.T) { testReserveIndexes(t, newDB(t)) })
+	t.Run("ReserveKey", func(t *testing.T) { testReserveKey(t, newDB(t)) })
+	t.Run("ConcurrentClaims", func(t *testing.T) { testConcurrentClaims(t, newDB(t)) })
+	t.Run("ConcurrentReserve", func(t *testing.T) { testConcurrentReserve(t, newDB(t)) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, newDB(t)) })
+}
+
+// writeKeys writes the given keys, failing the test on the first error.
+func writeKeys(t *testing.T, db repository.KGSDatabase, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if err := db.WriteKey(context.Background(), key); err != nil {
+			t.Fatalf("Error writing key %v: %v.\n", key, err)
+		}
+	}
+}
+
+// keyCount returns the amount of unused keys, failing the test on error.
+func keyCount(t *testing.T, db repository.KGSDatabase) int {
+	t.Helper()
+	count, err := db.KeyCount(context.Background())
+	if err != nil {
+		t.Fatalf("Error counting keys: %v.\n", err)
+	}
+	return count
+}
+
+func testKeyExist(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+
+	// 1. Key that doesn't exist.
+	ok, err := db.KeyExist(ctx, "key1")
+	if !errors.Is(err, repository.ErrKeyNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyNotFound)
+	}
+	if ok {
+		t.Errorf("Error shouldn't have found a key that doesn't exist.\n")
+	}
+
+	// 2. Keys exist whether they're unused, leased or used.
+	writeKeys(t, db, "key1", "key2", "key3")
+	if _, err := db.GetKeys(ctx, 1); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	if _, err := db.LeaseKeys(ctx, 1, time.Minute); err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	for _, key := range []string{"key1", "key2", "key3"} {
+		if ok, err := db.KeyExist(ctx, key); !ok || err != nil {
+			t.Errorf("Error checking key existence of %v: Have %v and %v, want true and no error.\n", key, ok, err)
+		}
+	}
+}
+
+func testWriteKey(t *testing.T, db repository.KGSDatabase) {
+	if count := keyCount(t, db); count != 0 {
+		t.Fatalf("Error new database isn't empty: Have %v keys.\n", count)
+	}
+
+	writeKeys(t, db, "key1", "key2", "key_3")
+	if count := keyCount(t, db); count != 3 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 3)
+	}
+
+	// Writing a key that is unused already, as racing generators may, is a no-op.
+	writeKeys(t, db, "key1", "key1")
+	if count := keyCount(t, db); count != 3 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 3)
+	}
+}
+
+func testGetKeys(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	written := map[string]bool{"key1": true, "key2": true, "key3": true, "key4": true, "key5": true}
+	for key := range written {
+		writeKeys(t, db, key)
+	}
+
+	// 1. Invalid amounts, or more keys than the pool holds, claim nothing.
+	for _, requiredKeys := range []int{-1, 0, len(written) + 1} {
+		if _, err := db.GetKeys(ctx, requiredKeys); !errors.Is(err, repository.ErrKeyOOR) {
+			t.Errorf("Error incorrect error for %v keys: Have %v, want %v.\n", requiredKeys, err, repository.ErrKeyOOR)
+		}
+	}
+	if count := keyCount(t, db); count != len(written) {
+		t.Errorf("Error failed claims shouldn't change the pool: Have %v keys, want %v.\n", count, len(written))
+	}
+
+	// 2. Claimed keys are written keys, and never handed out twice.
+	for _, requiredKeys := range []int{3, 2} {
+		keys, err := db.GetKeys(ctx, requiredKeys)
+		if err != nil {
+			t.Fatalf("Error getting keys: %v.\n", err)
+		}
+		if len(keys) != requiredKeys {
+			t.Errorf("Error incorrect amount of keys: Have %v, want %v.\n", len(keys), requiredKeys)
+		}
+		for _, key := range keys {
+			if !written[key] {
+				t.Errorf("Error key %q is either handed out twice or was never written.\n", key)
+			}
+			written[key] = false
+		}
+	}
+
+	// 3. The pool is empty.
+	if count := keyCount(t, db); count != 0 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 0)
+	}
+	if _, err := db.GetKeys(ctx, 1); !errors.Is(err, repository.ErrKeyOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	}
+}
+
+func testGetKeysAtLeast(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	writeKeys(t, db, "key_1", "key1", "key_2", "key2", "key_3", "key3", "key__1")
+
+	// 1. Only keys at least minLength long are claimed, shortest first.
+	keys, err := db.GetKeysAtLeast(ctx, 5, 2)
+	if err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	for _, key := range keys {
+		if len(key) != 5 {
+			t.Errorf("Error incorrect key length: Have %v, want %v.\n", len(key), 5)
+		}
+	}
+
+	// 2. Without a minimum, shortest keys are claimed first.
+	keys, err = db.GetKeys(ctx, 4)
+	if err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	lengths := make(map[int]int)
+	for _, key := range keys {
+		lengths[len(key)]++
+	}
+	if lengths[4] != 3 || lengths[5] != 1 {
+		t.Errorf("Error incorrect key lengths: Have %v, want three keys of length 4 and one of length 5.\n", keys)
+	}
+
+	// 3. Not enough keys that are long enough.
+	if _, err := db.GetKeysAtLeast(ctx, 6, 2); !errors.Is(err, repository.ErrKeyOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	}
+	if count := keyCount(t, db); count != 1 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 1)
+	}
+}
+
+func testStats(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	writeKeys(t, db, "key1", "key2", "key3", "key4", "key_1", "key_2")
+
+	if _, err := db.GetKeys(ctx, 2); err != nil {
+		t.Fatalf("Error getting keys: %v.\n", err)
+	}
+	if _, err := db.LeaseKeys(ctx, 3, time.Minute); err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting stats: %v.\n", err)
+	}
+	if want := (repository.Counts{Unused: 1, Leased: 3, Used: 2}); stats.Counts != want {
+		t.Errorf("Error incorrect stats: Have %+v, want %+v.\n", stats.Counts, want)
+	}
+	if want := (repository.Counts{Leased: 2, Used: 2}); stats.ByLength[4] != want {
+		t.Errorf("Error incorrect stats of length 4: Have %+v, want %+v.\n", stats.ByLength[4], want)
+	}
+	if want := (repository.Counts{Unused: 1, Leased: 1}); stats.ByLength[5] != want {
+		t.Errorf("Error incorrect stats of length 5: Have %+v, want %+v.\n", stats.ByLength[5], want)
+	}
+}
+
+func testLeases(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	writeKeys(t, db, "key1", "key2", "key3", "key4", "key5", "key6")
+
+	// 1. Invalid amounts lease nothing.
+	for _, requiredKeys := range []int{0, 7} {
+		if _, err := db.LeaseKeys(ctx, requiredKeys, time.Minute); !errors.Is(err, repository.ErrKeyOOR) {
+			t.Errorf("Error incorrect error for %v keys: Have %v, want %v.\n", requiredKeys, err, repository.ErrKeyOOR)
+		}
+	}
+
+	lease, err := db.LeaseKeys(ctx, 4, time.Minute)
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	if len(lease.Keys) != 4 || lease.ID == "" || !lease.ExpiresAt.After(time.Now()) {
+		t.Fatalf("Error incorrect lease: %+v.\n", lease)
+	}
+	if count := keyCount(t, db); count != 2 {
+		t.Errorf("Error leased keys shouldn't be unused: Have %v unused keys, want %v.\n", count, 2)
+	}
+
+	// 2. Unknown leases and keys outside the lease settle nothing.
+	if err := db.ConfirmKeys(ctx, "unknown", lease.Keys); !errors.Is(err, repository.ErrLeaseNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrLeaseNotFound)
+	}
+	if err := db.ReleaseKeys(ctx, "unknown", nil); !errors.Is(err, repository.ErrLeaseNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrLeaseNotFound)
+	}
+	if err := db.ConfirmKeys(ctx, lease.ID, []string{lease.Keys[0], "none"}); !errors.Is(err, repository.ErrKeyNotLeased) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyNotLeased)
+	}
+
+	// 3. Confirm one key, release another, then release the rest.
+	if err := db.ConfirmKeys(ctx, lease.ID, []string{lease.Keys[0]}); err != nil {
+		t.Errorf("Error confirming keys: %v.\n", err)
+	}
+	if err := db.ReleaseKeys(ctx, lease.ID, []string{lease.Keys[1]}); err != nil {
+		t.Errorf("Error releasing keys: %v.\n", err)
+	}
+	if err := db.ConfirmKeys(ctx, lease.ID, []string{lease.Keys[0]}); !errors.Is(err, repository.ErrKeyNotLeased) {
+		t.Errorf("Error settled keys shouldn't be in the lease: Have %v, want %v.\n", err, repository.ErrKeyNotLeased)
+	}
+	if err := db.ReleaseKeys(ctx, lease.ID, nil); err != nil {
+		t.Errorf("Error releasing keys: %v.\n", err)
+	}
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting stats: %v.\n", err)
+	}
+	if want := (repository.Counts{Unused: 5, Used: 1}); stats.Counts != want {
+		t.Errorf("Error incorrect stats: Have %+v, want %+v.\n", stats.Counts, want)
+	}
+
+	// 4. A fully settled lease is gone.
+	if err := db.ReleaseKeys(ctx, lease.ID, nil); !errors.Is(err, repository.ErrLeaseNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrLeaseNotFound)
+	}
+}
+
+func testExpireLeases(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	writeKeys(t, db, "key1", "key2", "key3", "key4", "key5")
+
+	if _, err := db.LeaseKeys(ctx, 2, time.Minute); err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	expiring, err := db.LeaseKeys(ctx, 3, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// 1. Keys of an expired lease can't be settled.
+	if err := db.ConfirmKeys(ctx, expiring.ID, expiring.Keys); !errors.Is(err, repository.ErrLeaseNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrLeaseNotFound)
+	}
+
+	// 2. They return to the pool, keys of active leases stay leased.
+	expired, err := db.ExpireLeases(ctx)
+	if err != nil {
+		t.Fatalf("Error expiring leases: %v.\n", err)
+	}
+	if expired != 3 {
+		t.Errorf("Error incorrect expired keys: Have %v, want %v.\n", expired, 3)
+	}
+	if count := keyCount(t, db); count != 3 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 3)
+	}
+
+	// 3. Expiring again is a no-op.
+	if expired, err := db.ExpireLeases(ctx); err != nil || expired != 0 {
+		t.Errorf("Error expiring leases twice: Have %v keys and %v, want 0 keys and no error.\n", expired, err)
+	}
+}
+
+func testReserveIndexes(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+
+	// Reservations are consecutive per key length, reserving 0 only peeks.
+	cases := []struct {
+		keyLength int
+		n         int
+		want      uint64
+	}{{4, 10, 0}, {4, 0, 10}, {4, 5, 10}, {5, 3, 0}, {4, 0, 15}, {5, 0, 3}}
+	for _, c := range cases {
+		start, err := db.ReserveIndexes(ctx, c.keyLength, c.n)
+		if err != nil {
+			t.Errorf("Error reserving indexes: %v.\n", err)
+		}
+		if start != c.want {
+			t.Errorf("Error incorrect start index: Have %v, want %v.\n", start, c.want)
+		}
+	}
+
+	if _, err := db.ReserveIndexes(ctx, 4, -1); !errors.Is(err, repository.ErrKeyOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	}
+}
+
+func testReserveKey(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+	writeKeys(t, db, "key1", "key2", "key3")
+	lease, err := db.LeaseKeys(ctx, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("Error leasing keys: %v.\n", err)
+	}
+
+	var unused []string
+	for _, key := range []string{"key1", "key2", "key3"} {
+		if key != lease.Keys[0] {
+			unused = append(unused, key)
+		}
+	}
+
+	// 1. Unused keys and keys that aren't stored yet are reserved, leased and used keys are not.
+	cases := []struct {
+		alias string
+		want  error
+	}{
+		{"launch", nil},
+		{unused[0], nil},
+		{unused[1], nil},
+		{lease.Keys[0], repository.ErrKeyUsed},
+		{"launch", repository.ErrKeyUsed},
+	}
+	for _, c := range cases {
+		if err := db.ReserveKey(ctx, c.alias); !errors.Is(err, c.want) {
+			t.Errorf("Error incorrect error reserving %v: Have %v, want %v.\n", c.alias, err, c.want)
+		}
+	}
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting stats: %v.\n", err)
+	}
+	if want := (repository.Counts{Unused: 0, Leased: 1, Used: 3}); stats.Counts != want {
+		t.Errorf("Error incorrect counts: Have %+v, want %+v.\n", stats.Counts, want)
+	}
+	if ok, err := db.KeyExist(ctx, "launch"); !ok || err != nil {
+		t.Errorf("Error checking key existence of %v: Have %v and %v, want true and no error.\n", "launch", ok, err)
+	}
+
+	// 2. Reserved keys are never handed out, even if they're written again.
+	writeKeys(t, db, "launch", "key4")
+	keys, err := db.GetKeys(ctx, 1)
+	if err != nil || len(keys) != 1 || keys[0] != "key4" {
+		t.Errorf("Error incorrect keys: Have %v and %v, want %v.\n", keys, err, []string{"key4"})
+	}
+	if _, err := db.GetKeys(ctx, 1); !errors.Is(err, repository.ErrKeyOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	}
+}
+
+func testConcurrentClaims(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+
+	callers, requiredKeys := 20, 10
+	pool := callers * requiredKeys
+	for i := 0; i < pool; i++ {
+		writeKeys(t, db, fmt.Sprintf("key%04d", i))
+	}
+
+	// Half of the callers get keys, the other half lease them. Together they ask for exactly the whole pool.
+	var wg sync.WaitGroup
+	results := make(chan []string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(lease bool) {
+			defer wg.Done()
+			var keys []string
+			var err error
+			if lease {
+				var l repository.Lease
+				l, err = db.LeaseKeys(ctx, requiredKeys, time.Minute)
+				keys = l.Keys
+			} else {
+				keys, err = db.GetKeys(ctx, requiredKeys)
+			}
+			if err != nil {
+				t.Errorf("Error claiming keys: %v.\n", err)
+				return
+			}
+			if len(keys) != requiredKeys {
+				t.Errorf("Error incorrect amount of keys: Have %v, want %v.\n", len(keys), requiredKeys)
+			}
+			results <- keys
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	close(results)
+
+	// Every key is handed to exactly one caller.
+	seen := make(map[string]struct{}, pool)
+	for keys := range results {
+		for _, key := range keys {
+			if key == "" {
+				t.Errorf("Error empty key handed out.\n")
+			}
+			if _, ok := seen[key]; ok {
+				t.Errorf("Error key %v is handed out more than once.\n", key)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+	if len(seen) != pool {
+		t.Errorf("Error incorrect amount of claimed keys: Have %v, want %v.\n", len(seen), pool)
+	}
+	if count := keyCount(t, db); count != 0 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 0)
+	}
+}
+
+func testConcurrentReserve(t *testing.T, db repository.KGSDatabase) {
+	ctx := context.Background()
+
+	// Every key is written and reserved at the same time. Whichever comes first, the key must end up used only.
+	keys := 100
+	var wg sync.WaitGroup
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key%04d", i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := db.WriteKey(ctx, key); err != nil {
+				t.Errorf("Error writing key %v: %v.\n", key, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := db.ReserveKey(ctx, key); err != nil {
+				t.Errorf("Error reserving key %v: %v.\n", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Error getting stats: %v.\n", err)
+	}
+	if want := (repository.Counts{Unused: 0, Leased: 0, Used: keys}); stats.Counts != want {
+		t.Errorf("Error incorrect counts: Have %+v, want %+v.\n", stats.Counts, want)
+	}
+	if _, err := db.GetKeys(ctx, 1); !errors.Is(err, repository.ErrKeyOOR) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyOOR)
+	}
+}
+
+func testContextCancellation(t *testing.T, db repository.KGSDatabase) {
+	writeKeys(t, db, "key1", "key2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := map[string]func() error{
+		"KeyExist": func() error { _, err := db.KeyExist(ctx, "key1"); return err },
+		"WriteKey": func() error { return db.WriteKey(ctx, "key3") },
+		"GetKeys":  func() error { _, err := db.GetKeys(ctx, 1); return err },
+		"GetKeysAtLeast": func() error {
+			_, err := db.GetKeysAtLeast(ctx, 4, 1)
+			return err
+		},
+		"KeyCount":       func() error { _, err := db.KeyCount(ctx); return err },
+		"Stats":          func() error { _, err := db.Stats(ctx); return err },
+		"LeaseKeys":      func() error { _, err := db.LeaseKeys(ctx, 1, time.Minute); return err },
+		"ConfirmKeys":    func() error { return db.ConfirmKeys(ctx, "lease", []string{"key1"}) },
+		"ReleaseKeys":    func() error { return db.ReleaseKeys(ctx, "lease", nil) },
+		"ExpireLeases":   func() error { _, err := db.ExpireLeases(ctx); return err },
+		"ReserveIndexes": func() error { _, err := db.ReserveIndexes(ctx, 4, 1); return err },
+		"ReserveKey":     func() error { return db.ReserveKey(ctx, "key2") },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Error incorrect error of %v: Have %v, want %v.\n", name, err, context.Canceled)
+		}
+	}
+
+	// Nothing changed.
+	if count := keyCount(t, db); count != 2 {
+		t.Errorf("Error incorrect key count: Have %v, want %v.\n", count, 2)
+	}
+}
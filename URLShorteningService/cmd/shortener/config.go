@@ -0,0 +1,162 @@
+package main
+
+import (
+	"KeyGenerationService/client"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	backendMemory = "memory"
+	backendBolt   = "bolt"
+)
+
+var (
+	ErrUnknownBackend   = errors.New("error unknown database backend")
+	ErrInvalidEnv       = errors.New("error invalid environment variable")
+	ErrInvalidKGSTLS    = errors.New("error a Key Generation Service client certificate requires its key and a CA")
+	ErrTokenWithoutTLS  = errors.New("error a Key Generation Service token is only sent over TLS")
+	ErrInvalidKeyLength = errors.New("error cannot have key length equal or smaller than 0, or max key length smaller than key length")
+)
+
+// config holds everything needed to start the URL Shortening Service.
+// Each field can be set by a flag or an environment variable, flags take precedence.
+type config struct {
+	addr            string
+	shutdownTimeout time.Duration
+	baseURL         string
+
+	httpAddr           string
+	permanentRedirects bool
+	keyLength          int
+	maxKeyLength       int
+	aliasRules         client.AliasRules
+
+	backend  string
+	boltPath string
+
+	kgsAddr      string
+	kgsCAFile    string
+	kgsCertFile  string
+	kgsKeyFile   string
+	kgsToken     string
+	keyBatchSize int
+}
+
+// loadConfig parses args into a config, falling back to environment variables read by getenv and then to defaults.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	var cfg config
+	env := envReader{getenv: getenv}
+
+	fs := flag.NewFlagSet("shortener", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", env.string("SHORTENER_ADDR", ":50052"), "gRPC listen address")
+	fs.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", env.duration("SHORTENER_SHUTDOWN_TIMEOUT", 10*time.Second), "time to drain in-flight RPCs before forcing shutdown")
+	fs.StringVar(&cfg.baseURL, "base-url", env.string("SHORTENER_BASE_URL", "http://localhost:8080"), "URL short keys are appended to")
+	fs.StringVar(&cfg.httpAddr, "http-addr", env.string("SHORTENER_HTTP_ADDR", ":8080"), "HTTP listen address of the redirects")
+	fs.BoolVar(&cfg.permanentRedirects, "permanent-redirects", env.bool("SHORTENER_PERMANENT_REDIRECTS", false), "redirect URLs that never expire with 301 instead of 302")
+	fs.IntVar(&cfg.keyLength, "key-length", env.int("SHORTENER_KEY_LENGTH", 4), "length of the keys of the Key Generation Service")
+	fs.IntVar(&cfg.maxKeyLength, "max-key-length", env.int("SHORTENER_MAX_KEY_LENGTH", 0), "longest key length the Key Generation Service can grow to, defaults to the key length")
+	fs.IntVar(&cfg.aliasRules.MinLength, "alias-min-length", env.int("SHORTENER_ALIAS_MIN_LENGTH", client.DefaultAliasRules.MinLength), "shortest alias, must match the Key Generation Service")
+	fs.IntVar(&cfg.aliasRules.MaxLength, "alias-max-length", env.int("SHORTENER_ALIAS_MAX_LENGTH", client.DefaultAliasRules.MaxLength), "longest alias, must match the Key Generation Service")
+	fs.StringVar(&cfg.aliasRules.Alphabet, "alias-alphabet", env.string("SHORTENER_ALIAS_ALPHABET", client.DefaultAliasRules.Alphabet), "characters an alias can contain, must match the Key Generation Service")
+
+	fs.StringVar(&cfg.backend, "backend", env.string("SHORTENER_BACKEND", backendMemory), "database backend: memory or bolt")
+	fs.StringVar(&cfg.boltPath, "bolt-path", env.string("SHORTENER_BOLT_PATH", "shortener.db"), "database file of the bolt backend")
+
+	fs.StringVar(&cfg.kgsAddr, "kgs-addr", env.string("SHORTENER_KGS_ADDR", "localhost:50051"), "address of the Key Generation Service")
+	fs.StringVar(&cfg.kgsCAFile, "kgs-ca", env.string("SHORTENER_KGS_CA", ""), "CA verifying the Key Generation Service, connects over TLS if set")
+	fs.StringVar(&cfg.kgsCertFile, "kgs-cert", env.string("SHORTENER_KGS_CERT", ""), "client certificate presented to the Key Generation Service")
+	fs.StringVar(&cfg.kgsKeyFile, "kgs-key", env.string("SHORTENER_KGS_KEY", ""), "key of the client certificate")
+	fs.StringVar(&cfg.kgsToken, "kgs-token", env.string("SHORTENER_KGS_TOKEN", ""), "bearer token sent to the Key Generation Service")
+	fs.IntVar(&cfg.keyBatchSize, "key-batch-size", env.int("SHORTENER_KEY_BATCH_SIZE", 100), "amount of keys fetched from the Key Generation Service at a time")
+
+	if env.err != nil {
+		return config{}, env.err
+	}
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	if cfg.maxKeyLength == 0 {
+		cfg.maxKeyLength = cfg.keyLength
+	}
+
+	if cfg.keyLength <= 0 || cfg.maxKeyLength < cfg.keyLength {
+		return config{}, ErrInvalidKeyLength
+	}
+	if err := cfg.aliasRules.Validate(); err != nil {
+		return config{}, err
+	}
+	if cfg.backend != backendMemory && cfg.backend != backendBolt {
+		return config{}, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.backend)
+	}
+	if (cfg.kgsCertFile == "") != (cfg.kgsKeyFile == "") || (cfg.kgsCertFile != "" && cfg.kgsCAFile == "") {
+		return config{}, ErrInvalidKGSTLS
+	}
+	if cfg.kgsToken != "" && cfg.kgsCAFile == "" {
+		return config{}, ErrTokenWithoutTLS
+	}
+
+	return cfg, nil
+}
+
+// envReader reads typed environment variables, keeping the first parsing error.
+type envReader struct {
+	getenv func(string) string
+	err    error
+}
+
+func (e *envReader) string(name, fallback string) string {
+	if v := e.getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (e *envReader) int(name string, fallback int) int {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return n
+}
+
+func (e *envReader) bool(name string, fallback bool) bool {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return b
+}
+
+func (e *envReader) duration(name string, fallback time.Duration) time.Duration {
+	v := e.getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.setErr(name, v)
+		return fallback
+	}
+	return d
+}
+
+func (e *envReader) setErr(name, value string) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%w: %s=%q", ErrInvalidEnv, name, value)
+	}
+}
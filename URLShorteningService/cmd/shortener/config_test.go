@@ -0,0 +1,71 @@
+package main
+
+import (
+	"KeyGenerationService/client"
+	"errors"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Test defaults", func(t *testing.T) {
+		cfg, err := loadConfig(nil, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.backend != backendMemory || cfg.kgsAddr != "localhost:50051" || cfg.keyBatchSize != 100 || cfg.maxKeyLength != 4 {
+			t.Errorf("Error incorrect defaults: %+v.\n", cfg)
+		}
+		if cfg.aliasRules != client.DefaultAliasRules {
+			t.Errorf("Error incorrect alias rules: Have %v, want %v.\n", cfg.aliasRules, client.DefaultAliasRules)
+		}
+	})
+
+	t.Run("Test alias rules", func(t *testing.T) {
+		env := map[string]string{"SHORTENER_ALIAS_ALPHABET": "abc"}
+		args := []string{"-alias-min-length", "5", "-alias-max-length", "10"}
+		cfg, err := loadConfig(args, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		want := client.AliasRules{MinLength: 5, MaxLength: 10, Alphabet: "abc"}
+		if cfg.aliasRules != want {
+			t.Errorf("Error incorrect alias rules: Have %v, want %v.\n", cfg.aliasRules, want)
+		}
+	})
+
+	t.Run("Test flags take precedence over environment variables", func(t *testing.T) {
+		env := map[string]string{"SHORTENER_KEY_BATCH_SIZE": "500", "SHORTENER_BACKEND": backendBolt}
+		cfg, err := loadConfig([]string{"-key-batch-size", "20"}, func(name string) string { return env[name] })
+		if err != nil {
+			t.Fatalf("Error loading config: %v.\n", err)
+		}
+		if cfg.keyBatchSize != 20 || cfg.backend != backendBolt {
+			t.Errorf("Error incorrect config: %+v.\n", cfg)
+		}
+	})
+
+	t.Run("Test invalid values", func(t *testing.T) {
+		cases := []struct {
+			args []string
+			want error
+		}{
+			{[]string{"-backend", "mysql"}, ErrUnknownBackend},
+			{[]string{"-kgs-cert", "client.crt"}, ErrInvalidKGSTLS},
+			{[]string{"-kgs-cert", "client.crt", "-kgs-key", "client.key"}, ErrInvalidKGSTLS},
+			{[]string{"-kgs-token", "secret"}, ErrTokenWithoutTLS},
+			{[]string{"-key-length", "0"}, ErrInvalidKeyLength},
+			{[]string{"-key-length", "6", "-max-key-length", "5"}, ErrInvalidKeyLength},
+			{[]string{"-alias-min-length", "0"}, client.ErrInvalidRules},
+		}
+		for _, c := range cases {
+			if _, err := loadConfig(c.args, func(string) string { return "" }); !errors.Is(err, c.want) {
+				t.Errorf("Error incorrect error for %v: Have %v, want %v.\n", c.args, err, c.want)
+			}
+		}
+
+		env := map[string]string{"SHORTENER_KEY_BATCH_SIZE": "many"}
+		if _, err := loadConfig(nil, func(name string) string { return env[name] }); !errors.Is(err, ErrInvalidEnv) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidEnv)
+		}
+	})
+}
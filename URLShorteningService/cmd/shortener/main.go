@@ -0,0 +1,177 @@
+package main
+
+import (
+	"KeyGenerationService/client"
+	"URLShorteningService/internal/controller"
+	"URLShorteningService/internal/handler/HTTP"
+	"URLShorteningService/internal/handler/gRPC"
+	"URLShorteningService/internal/handler/gRPC/gen"
+	"URLShorteningService/internal/kgs"
+	"URLShorteningService/internal/repository"
+	"URLShorteningService/internal/repository/bolt"
+	"URLShorteningService/internal/repository/memory"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the URL Shortening Service and blocks until SIGINT or SIGTERM is received.
+func run(cfg config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := newDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	if c, ok := db.(io.Closer); ok {
+		defer func() { _ = c.Close() }()
+	}
+
+	dialOpts, err := kgsDialOptions(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.Dial(cfg.kgsAddr, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	keys, err := client.New(conn, client.WithBatchSize(cfg.keyBatchSize))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = keys.Close() }()
+	shortener, err := controller.New(db, keys, cfg.baseURL,
+		controller.WithKeyLength(cfg.keyLength, cfg.maxKeyLength),
+		controller.WithAliasRules(cfg.aliasRules),
+	)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", cfg.addr)
+	if err != nil {
+		return err
+	}
+	httpLis, err := net.Listen("tcp", cfg.httpAddr)
+	if err != nil {
+		_ = lis.Close()
+		return err
+	}
+
+	srv := grpc.NewServer()
+	gen.RegisterURLShorteningServiceServer(srv, gRPC.New(shortener))
+
+	var httpOpts []HTTP.Option
+	if cfg.permanentRedirects {
+		httpOpts = append(httpOpts, HTTP.WithPermanentRedirects())
+	}
+	httpSrv := &http.Server{Handler: HTTP.New(shortener, httpOpts...), ReadHeaderTimeout: 5 * time.Second}
+
+	serveDone := make(chan error, 2)
+	go func() {
+		log.Printf("URL Shortening Service listening on %s (backend: %s, Key Generation Service: %s).\n", lis.Addr(), cfg.backend, cfg.kgsAddr)
+		serveDone <- srv.Serve(lis)
+	}()
+	go func() {
+		log.Printf("Redirecting short URLs on %s.\n", httpLis.Addr())
+		serveDone <- httpSrv.Serve(httpLis)
+	}()
+
+	select {
+	case err := <-serveDone:
+		srv.Stop()
+		_ = httpSrv.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, draining in-flight RPCs and requests.")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		_ = httpSrv.Close()
+	}
+	shutdown(srv, cfg.shutdownTimeout)
+	return nil
+}
+
+// newDatabase creates the repository.URLDatabase selected by cfg.backend.
+func newDatabase(cfg config) (repository.URLDatabase, error) {
+	if cfg.backend == backendBolt {
+		return bolt.Open(cfg.boltPath)
+	}
+	return memory.New(), nil
+}
+
+// kgsDialOptions translates cfg into the credentials of the Key Generation Service connection.
+func kgsDialOptions(cfg config) ([]grpc.DialOption, error) {
+	if cfg.kgsCAFile == "" {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	pem, err := os.ReadFile(cfg.kgsCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no certificate in %s", ErrInvalidKGSTLS, cfg.kgsCAFile)
+	}
+	tlsConfig := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	if cfg.kgsCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.kgsCertFile, cfg.kgsKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	if cfg.kgsToken != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(kgs.TokenCredentials(cfg.kgsToken)))
+	}
+	return opts, nil
+}
+
+// shutdown stops srv gracefully, forcing it to stop if in-flight RPCs aren't done within timeout.
+func shutdown(srv *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		log.Println("Shutdown timeout exceeded, forcing stop.")
+		srv.Stop()
+	}
+}
@@ -0,0 +1,20 @@
+module URLShorteningService
+
+go 1.21.3
+
+require (
+	KeyGenerationService v0.0.0
+	go.etcd.io/bbolt v1.3.8
+	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.18.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231106174013-bbf56f31fb17 // indirect
+)
+
+replace KeyGenerationService => ../KeyGenerationService
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"KeyGenerationService/client"
+	"URLShorteningService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxURLLength is the longest long URL that can be shortened, most browsers don't follow longer ones.
+const MaxURLLength = 2048
+
+// MaxTTL is the longest time a short URL can be created to redirect for.
+const MaxTTL = 100 * 365 * 24 * time.Hour
+
+// maxKeyAttempts is how many keys CreateShortURL tries before giving up, in case a key already maps to a URL.
+// The Key Generation Service never hands out a key twice, so more than one attempt means the key pool was reset.
+const maxKeyAttempts = 3
+
+var (
+	ErrInvalidBaseURL = errors.New("error base url must be an absolute http or https url")
+	ErrInvalidURL     = errors.New("error long url must be an absolute http or https url")
+	ErrURLTooLong     = fmt.Errorf("error long url is longer than %d bytes", MaxURLLength)
+	ErrNoKey          = errors.New("error getting a key for the short url")
+	ErrRepoError      = errors.New("error repo failed")
+	ErrInvalidTTL     = errors.New("error cannot have a negative TTL, or one longer than 100 years")
+	ErrInvalidKey     = errors.New("error key isn't a valid short key")
+	ErrURLGone        = errors.New("error short url expired or was deleted")
+	ErrInvalidLengths = errors.New("error cannot have key length equal or smaller than 0, or max key length smaller than key length")
+	ErrInvalidAlias   = errors.New("error alias breaks the alias rules or is blocked by the Key Generation Service")
+	ErrAliasTaken     = errors.New("error alias is already taken")
+)
+
+// ShortenerError wraps the errors caused by a request, which the client can act on.
+type ShortenerError struct {
+	Err error
+}
+
+func (e *ShortenerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ShortenerError) Unwrap() error {
+	return e.Err
+}
+
+// KeySource hands out keys that were never used before, and reserves aliases chosen by clients so they are never
+// handed out. client.Buffer is a KeySource.
+type KeySource interface {
+	Next(ctx context.Context) (string, error)
+	ReserveKey(ctx context.Context, alias string) error
+}
+
+// Shortener is the core of the URL Shortening Service.
+type Shortener struct {
+	db      repository.URLDatabase
+	keys    KeySource
+	baseURL string
+	now     func() time.Time
+
+	// keyLength and maxKeyLength bound the length of valid keys. The Key Generation Service may grow its key length
+	// up to maxKeyLength while keys of shorter lengths keep resolving.
+	keyLength    int
+	maxKeyLength int
+
+	// aliasRules decide which aliases CreateAliasURL accepts and which keys resolve besides generated ones.
+	aliasRules client.AliasRules
+}
+
+// Option configures optional behaviour of Shortener.
+type Option func(*Shortener)
+
+// WithKeyLength sets the shortest and longest keys handed out by the Key Generation Service. Defaults to 4 and 4.
+func WithKeyLength(keyLength, maxKeyLength int) Option {
+	return func(s *Shortener) {
+		s.keyLength = keyLength
+		s.maxKeyLength = maxKeyLength
+	}
+}
+
+// WithAliasRules sets which aliases short URLs can be created with. They must match the alias rules of the Key
+// Generation Service. Defaults to client.DefaultAliasRules.
+func WithAliasRules(rules client.AliasRules) Option {
+	return func(s *Shortener) {
+		s.aliasRules = rules
+	}
+}
+
+// New creates a new instance of Shortener. Short URLs are baseURL followed by their key.
+func New(db repository.URLDatabase, keys KeySource, baseURL string, opts ...Option) (*Shortener, error) {
+	if !isHTTPURL(baseURL) {
+		return nil, ErrInvalidBaseURL
+	}
+
+	s := &Shortener{
+		db:           db,
+		keys:         keys,
+		baseURL:      strings.TrimSuffix(baseURL, "/") + "/",
+		now:          time.Now,
+		keyLength:    4,
+		maxKeyLength: 4,
+		aliasRules:   client.DefaultAliasRules,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.keyLength <= 0 || s.maxKeyLength < s.keyLength {
+		return nil, ErrInvalidLengths
+	}
+	if err := s.aliasRules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// CreateShortURL maps a fresh key to longURL and returns the stored mapping. The short URL stops redirecting after
+// ttl, or never if ttl is zero.
+func (s *Shortener) CreateShortURL(ctx context.Context, longURL string, ttl time.Duration) (repository.URL, error) {
+	if err := validateURL(longURL, ttl); err != nil {
+		return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create short url error", err)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	for attempt := 0; attempt < maxKeyAttempts; attempt++ {
+		key, err := s.keys.Next(ctrlCtx)
+		if err != nil {
+			log.Println(err)
+			return repository.URL{}, fmt.Errorf("%w: %w", ErrNoKey, err)
+		}
+
+		u := s.newURL(key, longURL, ttl)
+		err = s.db.SaveURL(ctrlCtx, u)
+		if err == nil {
+			return u, nil
+		}
+		if !errors.Is(err, repository.ErrKeyExists) {
+			log.Println(err)
+			return repository.URL{}, repoError(err)
+		}
+		log.Printf("Key %q already maps to a url, trying the next key.\n", key)
+	}
+	return repository.URL{}, fmt.Errorf("%w: %w", ErrNoKey, repository.ErrKeyExists)
+}
+
+// CreateAliasURL maps alias, a key chosen by the client, to longURL and returns the stored mapping. The alias is
+// reserved at the Key Generation Service first, so it's never handed out as a generated key.
+func (s *Shortener) CreateAliasURL(ctx context.Context, longURL string, alias string, ttl time.Duration) (repository.URL, error) {
+	if err := validateURL(longURL, ttl); err != nil {
+		return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create alias url error", err)}
+	}
+	if !s.aliasRules.Valid(alias) {
+		return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create alias url error", ErrInvalidAlias)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := s.keys.ReserveKey(ctrlCtx, alias); err != nil {
+		switch {
+		case errors.Is(err, client.ErrInvalidAlias):
+			return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create alias url error", ErrInvalidAlias)}
+		case errors.Is(err, client.ErrAliasUsed):
+			return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create alias url error", ErrAliasTaken)}
+		default:
+			log.Println(err)
+			return repository.URL{}, fmt.Errorf("%w: %w", ErrNoKey, err)
+		}
+	}
+
+	u := s.newURL(alias, longURL, ttl)
+	if err := s.db.SaveURL(ctrlCtx, u); err != nil {
+		if errors.Is(err, repository.ErrKeyExists) {
+			return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Create alias url error", ErrAliasTaken)}
+		}
+		log.Println(err)
+		return repository.URL{}, repoError(err)
+	}
+	return u, nil
+}
+
+// newURL returns the mapping of key to longURL created now, expiring after ttl unless it's zero.
+func (s *Shortener) newURL(key string, longURL string, ttl time.Duration) repository.URL {
+	u := repository.URL{Key: key, LongURL: longURL, CreatedAt: s.now().UTC()}
+	if ttl > 0 {
+		u.ExpiresAt = u.CreatedAt.Add(ttl)
+	}
+	return u
+}
+
+// Resolve returns the URL a key redirects to. Keys that can neither have been handed out by the Key Generation
+// Service nor be an alias are rejected before the database is asked.
+func (s *Shortener) Resolve(ctx context.Context, key string) (repository.URL, error) {
+	if !s.resolvable(key) {
+		return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Resolve error", ErrInvalidKey)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	u, err := s.db.GetURL(ctrlCtx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			return repository.URL{}, &ShortenerError{Err: fmt.Errorf("%s: %w", "Resolve error", repository.ErrURLNotFound)}
+		}
+		log.Println(err)
+		return repository.URL{}, repoError(err)
+	}
+	if u.Deleted() || u.Expired(s.now()) {
+		return u, &ShortenerError{Err: fmt.Errorf("%s: %w", "Resolve error", ErrURLGone)}
+	}
+	return u, nil
+}
+
+// DeleteShortURL makes the short URL of key stop redirecting. The key is never reused.
+func (s *Shortener) DeleteShortURL(ctx context.Context, key string) error {
+	if !s.resolvable(key) {
+		return &ShortenerError{Err: fmt.Errorf("%s: %w", "Delete short url error", ErrInvalidKey)}
+	}
+
+	ctrlCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := s.db.DeleteURL(ctrlCtx, key, s.now().UTC()); err != nil {
+		if errors.Is(err, repository.ErrURLNotFound) {
+			return &ShortenerError{Err: fmt.Errorf("%s: %w", "Delete short url error", repository.ErrURLNotFound)}
+		}
+		log.Println(err)
+		return repoError(err)
+	}
+	return nil
+}
+
+// ValidKey checks whether key can have been handed out by the Key Generation Service, i.e. has a valid key length
+// and only consists of client.LetterBytes.
+func (s *Shortener) ValidKey(key string) bool {
+	if len(key) < s.keyLength || len(key) > s.maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(client.LetterBytes, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvable checks whether key is either a valid generated key or an alias following the alias rules.
+func (s *Shortener) resolvable(key string) bool {
+	return s.ValidKey(key) || s.aliasRules.Valid(key)
+}
+
+// ShortURL returns the short URL of key.
+func (s *Shortener) ShortURL(key string) string {
+	return s.baseURL + key
+}
+
+// validateURL checks whether a short URL can redirect to longURL for ttl.
+func validateURL(longURL string, ttl time.Duration) error {
+	switch {
+	case ttl < 0 || ttl > MaxTTL:
+		return ErrInvalidTTL
+	case len(longURL) > MaxURLLength:
+		return ErrURLTooLong
+	case !isHTTPURL(longURL):
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// isHTTPURL checks whether raw is an absolute http or https URL with a host.
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// repoError returns ErrRepoError for a failed repository call, wrapping err as well if the call was cancelled or
+// timed out.
+func repoError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrRepoError, err)
+	}
+	return ErrRepoError
+}
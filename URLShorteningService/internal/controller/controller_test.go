@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"KeyGenerationService/client"
+	"URLShorteningService/internal/repository"
+	"URLShorteningService/internal/repository/memory"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sliceKeySource hands out its keys in order, and fails once they're used up.
+// It reserves every alias once, except "admin", which it rejects like a blocked alias.
+type sliceKeySource struct {
+	mu       sync.Mutex
+	keys     []string
+	reserved map[string]bool
+}
+
+func (s *sliceKeySource) Next(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.keys) == 0 {
+		return "", errors.New("error no keys left")
+	}
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+	return key, nil
+}
+
+func (s *sliceKeySource) ReserveKey(_ context.Context, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if alias == "admin" {
+		return client.ErrInvalidAlias
+	}
+	if s.reserved[alias] {
+		return client.ErrAliasUsed
+	}
+	if s.reserved == nil {
+		s.reserved = make(map[string]bool)
+	}
+	s.reserved[alias] = true
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	baseURLCases := map[string]bool{
+		"http://localhost:8080": true,
+		"https://sho.rt/":       true,
+		"sho.rt":                false,
+		"ftp://sho.rt":          false,
+		"":                      false,
+	}
+	for baseURL, valid := range baseURLCases {
+		_, err := New(memory.New(), &sliceKeySource{}, baseURL)
+		if valid && err != nil {
+			t.Errorf("Error creating shortener for %q: %v.\n", baseURL, err)
+		} else if !valid && !errors.Is(err, ErrInvalidBaseURL) {
+			t.Errorf("Error incorrect error for %q: Have %v, want %v.\n", baseURL, err, ErrInvalidBaseURL)
+		}
+	}
+}
+
+func TestShortener_CreateShortURL(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	keys := &sliceKeySource{keys: []string{"aaaa", "bbbb", "cccc", "dddd"}}
+	s, err := New(db, keys, "https://sho.rt/")
+	if err != nil {
+		t.Fatalf("Error creating shortener: %v.\n", err)
+	}
+
+	// 1. Invalid long URLs are rejected without using a key.
+	longURLCases := []string{"", "example.com", "javascript:alert(1)", "https://" + strings.Repeat("a", MaxURLLength)}
+	for _, longURL := range longURLCases {
+		_, err := s.CreateShortURL(ctx, longURL, 0)
+		var ctrlErr *ShortenerError
+		if !errors.As(err, &ctrlErr) {
+			t.Errorf("Error incorrect error for %q: %v.\n", longURL, err)
+		}
+	}
+
+	// 2. A valid long URL is mapped to the next key.
+	u, err := s.CreateShortURL(ctx, "https://example.com/some/long/path?q=1", 0)
+	if err != nil {
+		t.Fatalf("Error creating short url: %v.\n", err)
+	}
+	if u.Key != "aaaa" || u.CreatedAt.IsZero() || s.ShortURL(u.Key) != "https://sho.rt/aaaa" {
+		t.Errorf("Error incorrect short url: %+v.\n", u)
+	}
+	if stored, err := db.GetURL(ctx, "aaaa"); err != nil || stored.LongURL != "https://example.com/some/long/path?q=1" {
+		t.Errorf("Error incorrect stored url: %+v (%v).\n", stored, err)
+	}
+
+	// 3. A key that already maps to a URL is skipped.
+	_ = db.SaveURL(ctx, repository.URL{Key: "bbbb", LongURL: "https://example.org"})
+	if u, err := s.CreateShortURL(ctx, "https://example.com", 0); err != nil || u.Key != "cccc" {
+		t.Errorf("Error incorrect key: Have %v (%v), want %v.\n", u.Key, err, "cccc")
+	}
+
+	// 4. A negative or too long TTL is rejected.
+	for _, ttl := range []time.Duration{-time.Second, MaxTTL + time.Second} {
+		if _, err := s.CreateShortURL(ctx, "https://example.com", ttl); !errors.Is(err, ErrInvalidTTL) {
+			t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidTTL)
+		}
+	}
+
+	// 5. Running out of keys is reported.
+	_, _ = s.CreateShortURL(ctx, "https://example.com", 0)
+	if _, err := s.CreateShortURL(ctx, "https://example.com", 0); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrNoKey)
+	}
+}
+
+func TestShortener_Resolve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	keys := &sliceKeySource{keys: []string{"aaaa", "bbbbb", "cccc"}}
+	rules := client.AliasRules{MinLength: 8, MaxLength: 16, Alphabet: client.LetterBytes + "-"}
+	s, err := New(memory.New(), keys, "https://sho.rt", WithKeyLength(4, 5), WithAliasRules(rules))
+	if err != nil {
+		t.Fatalf("Error creating shortener: %v.\n", err)
+	}
+	s.now = func() time.Time { return now }
+
+	_, _ = s.CreateShortURL(ctx, "https://example.com/a", 0)
+	_, _ = s.CreateShortURL(ctx, "https://example.com/b", time.Minute)
+	_, _ = s.CreateShortURL(ctx, "https://example.com/c", 0)
+	if err := s.DeleteShortURL(ctx, "cccc"); err != nil {
+		t.Fatalf("Error deleting short url: %v.\n", err)
+	}
+	now = now.Add(time.Minute)
+
+	cases := []struct {
+		key  string
+		want error
+	}{
+		{"aaaa", nil},
+		{"bbbbb", ErrURLGone},
+		{"cccc", ErrURLGone},
+		{"dddd", repository.ErrURLNotFound},
+		{"aaa", ErrInvalidKey},
+		{"aaaaaa", ErrInvalidKey},
+		{"aa_a", ErrInvalidKey},
+		{"", ErrInvalidKey},
+		{"my-promo", repository.ErrURLNotFound},
+		{"my_promo", ErrInvalidKey},
+	}
+	for _, c := range cases {
+		u, err := s.Resolve(ctx, c.key)
+		if !errors.Is(err, c.want) {
+			t.Errorf("Error incorrect error for %q: Have %v, want %v.\n", c.key, err, c.want)
+		}
+		if c.want == nil && u.LongURL != "https://example.com/a" {
+			t.Errorf("Error incorrect long url: Have %v, want %v.\n", u.LongURL, "https://example.com/a")
+		}
+	}
+
+	// Aliases resolve, but they are no valid generated keys.
+	if s.ValidKey("my-promo") || !s.ValidKey("aaaa") {
+		t.Errorf("Error incorrect key validation: Have %v and %v, want false and true.\n", s.ValidKey("my-promo"), s.ValidKey("aaaa"))
+	}
+
+	if err := s.DeleteShortURL(ctx, "dddd"); !errors.Is(err, repository.ErrURLNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrURLNotFound)
+	}
+	if _, err := New(memory.New(), keys, "https://sho.rt", WithKeyLength(5, 4)); !errors.Is(err, ErrInvalidLengths) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, ErrInvalidLengths)
+	}
+	if _, err := New(memory.New(), keys, "https://sho.rt", WithAliasRules(client.AliasRules{})); !errors.Is(err, client.ErrInvalidRules) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, client.ErrInvalidRules)
+	}
+}
+
+func TestShortener_CreateAliasURL(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(memory.New(), &sliceKeySource{}, "https://sho.rt")
+	if err != nil {
+		t.Fatalf("Error creating shortener: %v.\n", err)
+	}
+
+	// 1. Aliases following the alias rules are reserved once, the Key Generation Service may still reject them.
+	cases := []struct {
+		longURL string
+		alias   string
+		want    error
+	}{
+		{"https://example.com/launch", "launch", nil},
+		{"https://example.com/promo", "my-promo", nil},
+		{"https://example.com/launch", "launch", ErrAliasTaken},
+		{"https://example.com/admin", "admin", ErrInvalidAlias},
+		{"https://example.com/short", "ab", ErrInvalidAlias},
+		{"https://example.com/slash", "a/b/c", ErrInvalidAlias},
+		{"example.com", "invalid-url", ErrInvalidURL},
+	}
+	for _, c := range cases {
+		u, err := s.CreateAliasURL(ctx, c.longURL, c.alias, 0)
+		var ctrlErr *ShortenerError
+		if !errors.Is(err, c.want) || (c.want != nil && !errors.As(err, &ctrlErr)) {
+			t.Errorf("Error incorrect error for %v: Have %v, want %v.\n", c.alias, err, c.want)
+		}
+		if c.want == nil && (u.Key != c.alias || s.ShortURL(u.Key) != "https://sho.rt/"+c.alias) {
+			t.Errorf("Error incorrect short url: %+v.\n", u)
+		}
+	}
+
+	// 2. Short URLs of aliases resolve like any other.
+	u, err := s.Resolve(ctx, "my-promo")
+	if err != nil || u.LongURL != "https://example.com/promo" {
+		t.Errorf("Error resolving alias: Have %v (%v), want %v.\n", u.LongURL, err, "https://example.com/promo")
+	}
+}
@@ -0,0 +1,74 @@
+package HTTP
+
+import (
+	"URLShorteningService/internal/controller"
+	"URLShorteningService/internal/repository"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handler redirects GET /{key} to the long URL the key maps to.
+type Handler struct {
+	controller *controller.Shortener
+	// permanent makes URLs that never expire redirect with 301 Moved Permanently instead of 302 Found.
+	// Browsers cache 301 redirects, so expiring URLs always redirect with 302.
+	permanent bool
+}
+
+// Option configures optional behaviour of Handler.
+type Option func(*Handler)
+
+// WithPermanentRedirects makes URLs that never expire redirect with 301 Moved Permanently.
+// Browsers keep following a cached 301 even after the URL was deleted.
+func WithPermanentRedirects() Option {
+	return func(h *Handler) {
+		h.permanent = true
+	}
+}
+
+// New creates a new handler instance.
+func New(ctrl *controller.Shortener, opts ...Option) *Handler {
+	h := &Handler{controller: ctrl}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP resolves the key in the path of r. Unknown and invalid keys are 404 Not Found, expired and deleted ones
+// 410 Gone.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Keys never contain a '/', so only single segment paths can be short URLs.
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	u, err := h.controller.Resolve(r.Context(), key)
+	switch {
+	case err == nil:
+	case errors.Is(err, controller.ErrInvalidKey), errors.Is(err, repository.ErrURLNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, controller.ErrURLGone):
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+		return
+	default:
+		log.Println(err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	code := http.StatusFound
+	if h.permanent && u.ExpiresAt.IsZero() {
+		code = http.StatusMovedPermanently
+	} else {
+		// A cached redirect would outlive the expiry or deletion of the URL.
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	http.Redirect(w, r, u.LongURL, code)
+}
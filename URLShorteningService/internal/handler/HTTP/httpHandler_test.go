@@ -0,0 +1,95 @@
+package HTTP
+
+import (
+	"URLShorteningService/internal/controller"
+	"URLShorteningService/internal/repository"
+	"URLShorteningService/internal/repository/memory"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// keyList hands out its keys in order, and reserves every alias.
+type keyList []string
+
+func (k *keyList) Next(context.Context) (string, error) {
+	key := (*k)[0]
+	*k = (*k)[1:]
+	return key, nil
+}
+
+func (k *keyList) ReserveKey(context.Context, string) error {
+	return nil
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	keys := keyList{"perm", "temp", "dead"}
+	shortener, err := controller.New(db, &keys, "https://sho.rt", controller.WithKeyLength(4, 5))
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	if _, err := shortener.CreateShortURL(ctx, "https://example.com/permanent", 0); err != nil {
+		t.Fatalf("Error creating short url: %v.\n", err)
+	}
+	if _, err := shortener.CreateShortURL(ctx, "https://example.com/temporary", time.Hour); err != nil {
+		t.Fatalf("Error creating short url: %v.\n", err)
+	}
+	if _, err := shortener.CreateShortURL(ctx, "https://example.com/deleted", 0); err != nil {
+		t.Fatalf("Error creating short url: %v.\n", err)
+	}
+	if _, err := shortener.CreateAliasURL(ctx, "https://example.com/promo", "my-promo", 0); err != nil {
+		t.Fatalf("Error creating alias url: %v.\n", err)
+	}
+	if err := shortener.DeleteShortURL(ctx, "dead"); err != nil {
+		t.Fatalf("Error deleting short url: %v.\n", err)
+	}
+	expired := repository.URL{Key: "old1", LongURL: "https://example.com/expired", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := db.SaveURL(ctx, expired); err != nil {
+		t.Fatalf("Error saving url: %v.\n", err)
+	}
+
+	cases := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/perm", http.StatusMovedPermanently, "https://example.com/permanent"},
+		{http.MethodHead, "/perm", http.StatusMovedPermanently, "https://example.com/permanent"},
+		{http.MethodGet, "/temp", http.StatusFound, "https://example.com/temporary"},
+		{http.MethodGet, "/my-promo", http.StatusMovedPermanently, "https://example.com/promo"},
+		{http.MethodGet, "/dead", http.StatusGone, ""},
+		{http.MethodGet, "/old1", http.StatusGone, ""},
+		{http.MethodGet, "/none", http.StatusNotFound, ""},
+		{http.MethodGet, "/", http.StatusNotFound, ""},
+		{http.MethodGet, "/abc", http.StatusNotFound, ""},
+		{http.MethodGet, "/abcdef", http.StatusNotFound, ""},
+		{http.MethodGet, "/ab-d", http.StatusNotFound, ""},
+		{http.MethodGet, "/perm/x", http.StatusNotFound, ""},
+		{http.MethodPost, "/perm", http.StatusMethodNotAllowed, ""},
+	}
+
+	handler := New(shortener, WithPermanentRedirects())
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+		if rec.Code != c.code {
+			t.Errorf("Error incorrect status for %v %v: Have %v, want %v.\n", c.method, c.path, rec.Code, c.code)
+		}
+		if location := rec.Header().Get("Location"); location != c.location {
+			t.Errorf("Error incorrect location for %v %v: Have %v, want %v.\n", c.method, c.path, location, c.location)
+		}
+	}
+
+	// Without permanent redirects, every URL redirects with 302.
+	rec := httptest.NewRecorder()
+	New(shortener).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/perm", nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("Error incorrect status: Have %v, want %v.\n", rec.Code, http.StatusFound)
+	}
+}
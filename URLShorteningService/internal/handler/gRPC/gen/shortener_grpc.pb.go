@@ -0,0 +1,146 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.2
+// source: shortener.proto
+
+package gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	URLShorteningService_CreateShortURL_FullMethodName = "/URLShorteningService/CreateShortURL"
+	URLShorteningService_DeleteShortURL_FullMethodName = "/URLShorteningService/DeleteShortURL"
+)
+
+// URLShorteningServiceClient is the client API for URLShorteningService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type URLShorteningServiceClient interface {
+	CreateShortURL(ctx context.Context, in *CreateShortURLRequest, opts ...grpc.CallOption) (*CreateShortURLResponse, error)
+	DeleteShortURL(ctx context.Context, in *DeleteShortURLRequest, opts ...grpc.CallOption) (*DeleteShortURLResponse, error)
+}
+
+type uRLShorteningServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewURLShorteningServiceClient(cc grpc.ClientConnInterface) URLShorteningServiceClient {
+	return &uRLShorteningServiceClient{cc}
+}
+
+func (c *uRLShorteningServiceClient) CreateShortURL(ctx context.Context, in *CreateShortURLRequest, opts ...grpc.CallOption) (*CreateShortURLResponse, error) {
+	out := new(CreateShortURLResponse)
+	err := c.cc.Invoke(ctx, URLShorteningService_CreateShortURL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uRLShorteningServiceClient) DeleteShortURL(ctx context.Context, in *DeleteShortURLRequest, opts ...grpc.CallOption) (*DeleteShortURLResponse, error) {
+	out := new(DeleteShortURLResponse)
+	err := c.cc.Invoke(ctx, URLShorteningService_DeleteShortURL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// URLShorteningServiceServer is the server API for URLShorteningService service.
+// All implementations must embed UnimplementedURLShorteningServiceServer
+// for forward compatibility
+type URLShorteningServiceServer interface {
+	CreateShortURL(context.Context, *CreateShortURLRequest) (*CreateShortURLResponse, error)
+	DeleteShortURL(context.Context, *DeleteShortURLRequest) (*DeleteShortURLResponse, error)
+	mustEmbedUnimplementedURLShorteningServiceServer()
+}
+
+// UnimplementedURLShorteningServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedURLShorteningServiceServer struct {
+}
+
+func (UnimplementedURLShorteningServiceServer) CreateShortURL(context.Context, *CreateShortURLRequest) (*CreateShortURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateShortURL not implemented")
+}
+func (UnimplementedURLShorteningServiceServer) DeleteShortURL(context.Context, *DeleteShortURLRequest) (*DeleteShortURLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteShortURL not implemented")
+}
+func (UnimplementedURLShorteningServiceServer) mustEmbedUnimplementedURLShorteningServiceServer() {}
+
+// UnsafeURLShorteningServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to URLShorteningServiceServer will
+// result in compilation errors.
+type UnsafeURLShorteningServiceServer interface {
+	mustEmbedUnimplementedURLShorteningServiceServer()
+}
+
+func RegisterURLShorteningServiceServer(s grpc.ServiceRegistrar, srv URLShorteningServiceServer) {
+	s.RegisterService(&URLShorteningService_ServiceDesc, srv)
+}
+
+func _URLShorteningService_CreateShortURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateShortURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShorteningServiceServer).CreateShortURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLShorteningService_CreateShortURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShorteningServiceServer).CreateShortURL(ctx, req.(*CreateShortURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _URLShorteningService_DeleteShortURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteShortURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(URLShorteningServiceServer).DeleteShortURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: URLShorteningService_DeleteShortURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(URLShorteningServiceServer).DeleteShortURL(ctx, req.(*DeleteShortURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// URLShorteningService_ServiceDesc is the grpc.ServiceDesc for URLShorteningService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var URLShorteningService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "URLShorteningService",
+	HandlerType: (*URLShorteningServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateShortURL",
+			Handler:    _URLShorteningService_CreateShortURL_Handler,
+		},
+		{
+			MethodName: "DeleteShortURL",
+			Handler:    _URLShorteningService_DeleteShortURL_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "shortener.proto",
+}
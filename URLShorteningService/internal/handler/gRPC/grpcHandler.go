@@ -0,0 +1,89 @@
+package gRPC
+
+import (
+	"URLShorteningService/internal/controller"
+	"URLShorteningService/internal/handler/gRPC/gen"
+	"URLShorteningService/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Handler implements the generated gRPC server of the URL Shortening Service.
+type Handler struct {
+	gen.UnimplementedURLShorteningServiceServer
+	controller *controller.Shortener
+}
+
+// New creates a new handler instance.
+func New(ctrl *controller.Shortener) *Handler {
+	return &Handler{controller: ctrl}
+}
+
+// CreateShortURL accepts all incoming gen.CreateShortURLRequest and maps a fresh key, or the alias if one is set, to
+// the long URL.
+func (h *Handler) CreateShortURL(ctx context.Context, req *gen.CreateShortURLRequest) (*gen.CreateShortURLResponse, error) {
+	// Longer TTLs would overflow time.Duration, the controller rejects them anyway.
+	if req.TTLSeconds > int64(controller.MaxTTL/time.Second) {
+		err := &controller.ShortenerError{Err: fmt.Errorf("%s: %w", "Create short url error", controller.ErrInvalidTTL)}
+		return &gen.CreateShortURLResponse{Success: false}, statusError(err)
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	longURL := strings.TrimSpace(req.LongURL)
+	var u repository.URL
+	var err error
+	if req.Alias != "" {
+		u, err = h.controller.CreateAliasURL(ctx, longURL, req.Alias, ttl)
+	} else {
+		u, err = h.controller.CreateShortURL(ctx, longURL, ttl)
+	}
+	if err != nil {
+		return &gen.CreateShortURLResponse{Success: false}, statusError(err)
+	}
+
+	var expiresAt int64
+	if !u.ExpiresAt.IsZero() {
+		expiresAt = u.ExpiresAt.Unix()
+	}
+	return &gen.CreateShortURLResponse{
+		Success:   true,
+		Key:       u.Key,
+		ShortURL:  h.controller.ShortURL(u.Key),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// DeleteShortURL accepts all incoming gen.DeleteShortURLRequest and makes the short URL stop redirecting.
+func (h *Handler) DeleteShortURL(ctx context.Context, req *gen.DeleteShortURLRequest) (*gen.DeleteShortURLResponse, error) {
+	if err := h.controller.DeleteShortURL(ctx, req.Key); err != nil {
+		return &gen.DeleteShortURLResponse{Success: false}, statusError(err)
+	}
+	return &gen.DeleteShortURLResponse{Success: true}, nil
+}
+
+// statusError converts a controller error into a gRPC status.
+func statusError(err error) error {
+	var ctrlErr *controller.ShortenerError
+	switch {
+	case errors.Is(err, repository.ErrURLNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, controller.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.As(err, &ctrlErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, controller.ErrNoKey), errors.Is(err, controller.ErrRepoError):
+		// Both the Key Generation Service and the database may be back soon.
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
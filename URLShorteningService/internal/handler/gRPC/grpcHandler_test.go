@@ -0,0 +1,152 @@
+package gRPC
+
+import (
+	"KeyGenerationService/client"
+	"URLShorteningService/internal/controller"
+	"URLShorteningService/internal/handler/HTTP"
+	"URLShorteningService/internal/handler/gRPC/gen"
+	"URLShorteningService/internal/repository/memory"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// oneKey hands out a single key, and fails afterwards.
+type oneKey struct {
+	used bool
+}
+
+func (o *oneKey) Next(context.Context) (string, error) {
+	if o.used {
+		return "", errors.New("error no keys left")
+	}
+	o.used = true
+	return "aaaa", nil
+}
+
+// ReserveKey reserves every alias but "taken", which was handed out already.
+func (o *oneKey) ReserveKey(_ context.Context, alias string) error {
+	if alias == "taken" {
+		return client.ErrAliasUsed
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T) gen.URLShorteningServiceClient {
+	t.Helper()
+	conn, _ := newTestServer(t)
+	return conn
+}
+
+// newTestServer serves a shortener handing out the single key "aaaa", and returns a client and the shortener.
+func newTestServer(t *testing.T) (gen.URLShorteningServiceClient, *controller.Shortener) {
+	t.Helper()
+
+	shortener, err := controller.New(memory.New(), &oneKey{}, "https://sho.rt")
+	if err != nil {
+		t.Fatalf("Error creating controller: %v.\n", err)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	gen.RegisterURLShorteningServiceServer(srv, New(shortener))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing handler: %v.\n", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return gen.NewURLShorteningServiceClient(conn), shortener
+}
+
+func TestHandler_CreateShortURL(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: " https://example.com "})
+	if err != nil {
+		t.Fatalf("Error creating short url: %v.\n", err)
+	}
+	if !resp.Success || resp.Key != "aaaa" || resp.ShortURL != "https://sho.rt/aaaa" {
+		t.Errorf("Error incorrect response: %v.\n", resp)
+	}
+
+	if _, err := client.DeleteShortURL(ctx, &gen.DeleteShortURLRequest{Key: "bbbb"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.NotFound)
+	}
+	if del, err := client.DeleteShortURL(ctx, &gen.DeleteShortURLRequest{Key: "aaaa"}); err != nil || !del.Success {
+		t.Errorf("Error deleting short url: %v.\n", err)
+	}
+
+	_, err = client.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: "example.com"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.InvalidArgument)
+	}
+
+	_, err = client.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: "https://example.com", TTLSeconds: math.MaxInt64})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.InvalidArgument)
+	}
+
+	_, err = client.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: "https://example.com"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Error incorrect status code: Have %v, want %v.\n", status.Code(err), codes.Unavailable)
+	}
+}
+
+func TestHandler_CreateShortURL_Alias(t *testing.T) {
+	shortenerClient, shortener := newTestServer(t)
+	ctx := context.Background()
+
+	// 1. A short URL created with an alias redirects from the alias.
+	resp, err := shortenerClient.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: "https://example.com/promo", Alias: "my-promo"})
+	if err != nil {
+		t.Fatalf("Error creating alias url: %v.\n", err)
+	}
+	if !resp.Success || resp.Key != "my-promo" || resp.ShortURL != "https://sho.rt/my-promo" {
+		t.Errorf("Error incorrect response: %v.\n", resp)
+	}
+	rec := httptest.NewRecorder()
+	HTTP.New(shortener).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/my-promo", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/promo" {
+		t.Errorf("Error incorrect redirect: Have %v %v, want %v %v.\n", rec.Code, rec.Header().Get("Location"), http.StatusFound, "https://example.com/promo")
+	}
+
+	// 2. Taken and invalid aliases are rejected.
+	cases := []struct {
+		alias string
+		code  codes.Code
+	}{
+		{"my-promo", codes.AlreadyExists},
+		{"taken", codes.AlreadyExists},
+		{"a/b", codes.InvalidArgument},
+	}
+	for _, c := range cases {
+		_, err := shortenerClient.CreateShortURL(ctx, &gen.CreateShortURLRequest{LongURL: "https://example.com", Alias: c.alias})
+		if status.Code(err) != c.code {
+			t.Errorf("Error incorrect status code for %v: Have %v, want %v.\n", c.alias, status.Code(err), c.code)
+		}
+	}
+}
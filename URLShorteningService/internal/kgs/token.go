@@ -0,0 +1,17 @@
+package kgs
+
+import "context"
+
+// TokenCredentials sends a bearer token to the Key Generation Service with every call.
+// It implements credentials.PerRPCCredentials, and is only sent over TLS.
+type TokenCredentials string
+
+// GetRequestMetadata returns the authorization metadata of a call.
+func (t TokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity keeps the token from being sent in plaintext.
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,135 @@
+package bolt
+
+import (
+	"URLShorteningService/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// urlsBucket maps a key to its JSON encoded repository.URL.
+var urlsBucket = []byte("urls")
+
+// DB keeps short URLs in a bbolt file. Every write is synced to disk before it returns.
+// Like InMemoryDB, a context is only checked before an operation starts.
+type DB struct {
+	db *bbolt.DB
+}
+
+// Open opens or creates the database file at path. The file is locked while it's open,
+// so only one instance can use it at a time.
+func Open(path string) (*DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(urlsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+
+	return &DB{db: db}, nil
+}
+
+// Close closes the database file.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// SaveURL stores the mapping of url, unless its key already maps to a URL.
+func (d *DB) SaveURL(ctx context.Context, url repository.URL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(url)
+	if err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+
+	err = d.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(urlsBucket)
+		if b.Get([]byte(url.Key)) != nil {
+			return repository.ErrKeyExists
+		}
+		return b.Put([]byte(url.Key), value)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrKeyExists):
+		return err
+	default:
+		return fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+}
+
+// GetURL resolves key to its URL.
+func (d *DB) GetURL(ctx context.Context, key string) (repository.URL, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.URL{}, err
+	}
+
+	var url repository.URL
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(urlsBucket).Get([]byte(key))
+		if value == nil {
+			return repository.ErrURLNotFound
+		}
+		return json.Unmarshal(value, &url)
+	})
+	switch {
+	case err == nil:
+		return url, nil
+	case errors.Is(err, repository.ErrURLNotFound):
+		return repository.URL{}, err
+	default:
+		return repository.URL{}, fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+}
+
+// DeleteURL marks the URL of key as deleted at the given time.
+func (d *DB) DeleteURL(ctx context.Context, key string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(urlsBucket)
+		value := b.Get([]byte(key))
+		if value == nil {
+			return repository.ErrURLNotFound
+		}
+
+		var url repository.URL
+		if err := json.Unmarshal(value, &url); err != nil {
+			return err
+		}
+		if url.Deleted() {
+			return nil
+		}
+		url.DeletedAt = at
+		value, err := json.Marshal(url)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrURLNotFound):
+		return err
+	default:
+		return fmt.Errorf("%w: %v", repository.ErrDatabaseError, err)
+	}
+}
@@ -0,0 +1,65 @@
+package bolt
+
+import (
+	"URLShorteningService/internal/repository"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error opening database: %v.\n", err)
+	}
+
+	// 1. The file is locked while it's open.
+	if _, err := Open(path); !errors.Is(err, repository.ErrDatabaseError) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrDatabaseError)
+	}
+
+	// 2. A key maps to a single URL.
+	want := repository.URL{Key: "aaaa", LongURL: "https://example.com", CreatedAt: time.Unix(1700000000, 0).UTC()}
+	if err := db.SaveURL(ctx, want); err != nil {
+		t.Fatalf("Error saving url: %v.\n", err)
+	}
+	if err := db.SaveURL(ctx, repository.URL{Key: "aaaa", LongURL: "https://example.org"}); !errors.Is(err, repository.ErrKeyExists) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyExists)
+	}
+
+	// 3. URLs survive a restart.
+	if err := db.Close(); err != nil {
+		t.Fatalf("Error closing database: %v.\n", err)
+	}
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Error reopening database: %v.\n", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if u, err := db.GetURL(ctx, "aaaa"); err != nil || u != want {
+		t.Errorf("Error incorrect url: Have %+v (%v), want %+v.\n", u, err, want)
+	}
+	if _, err := db.GetURL(ctx, "bbbb"); !errors.Is(err, repository.ErrURLNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrURLNotFound)
+	}
+
+	// 4. Deleted URLs keep their key.
+	deletedAt := time.Unix(1700000100, 0).UTC()
+	if err := db.DeleteURL(ctx, "aaaa", deletedAt); err != nil {
+		t.Errorf("Error deleting url: %v.\n", err)
+	}
+	if u, _ := db.GetURL(ctx, "aaaa"); !u.DeletedAt.Equal(deletedAt) {
+		t.Errorf("Error incorrect deletion time: Have %v, want %v.\n", u.DeletedAt, deletedAt)
+	}
+	if err := db.SaveURL(ctx, repository.URL{Key: "aaaa"}); !errors.Is(err, repository.ErrKeyExists) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyExists)
+	}
+	if err := db.DeleteURL(ctx, "bbbb", deletedAt); !errors.Is(err, repository.ErrURLNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrURLNotFound)
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// URLDatabase is the interface that wraps storing and resolving short URLs.
+type URLDatabase interface {
+	// SaveURL stores the mapping of a URL, failing with ErrKeyExists if its key already maps to a URL.
+	SaveURL(context.Context, URL) error
+	// GetURL resolves a key to its URL, including expired and deleted ones.
+	GetURL(context.Context, string) (URL, error)
+	// DeleteURL marks the URL of a key as deleted. The key stays taken, so it never redirects anywhere else.
+	DeleteURL(ctx context.Context, key string, at time.Time) error
+}
+
+// URL maps a short key to the long URL it redirects to.
+type URL struct {
+	Key       string
+	LongURL   string
+	CreatedAt time.Time
+	// ExpiresAt is when the URL stops redirecting, it never does if zero.
+	ExpiresAt time.Time
+	// DeletedAt is when the URL was deleted, it is zero while the URL isn't.
+	DeletedAt time.Time
+}
+
+// Expired checks whether u expired at now.
+func (u URL) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
+}
+
+// Deleted checks whether u was deleted.
+func (u URL) Deleted() bool {
+	return !u.DeletedAt.IsZero()
+}
+
+var (
+	ErrURLNotFound   = errors.New("error desired key doesn't map to a url")
+	ErrKeyExists     = errors.New("error key already maps to a url")
+	ErrDatabaseError = errors.New("error database failed")
+)
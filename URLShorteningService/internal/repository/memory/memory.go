@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"URLShorteningService/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryDB keeps short URLs in memory, for tests and single node deployments that can lose their URLs.
+// Operations are instant, so a context is only checked before an operation starts.
+type InMemoryDB struct {
+	mu   sync.RWMutex
+	urls map[string]repository.URL
+}
+
+// New creates a new instance of InMemoryDB.
+func New() *InMemoryDB {
+	return &InMemoryDB{urls: make(map[string]repository.URL)}
+}
+
+// SaveURL stores the mapping of url, unless its key already maps to a URL.
+func (i *InMemoryDB) SaveURL(ctx context.Context, url repository.URL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.urls[url.Key]; ok {
+		return repository.ErrKeyExists
+	}
+	i.urls[url.Key] = url
+	return nil
+}
+
+// GetURL resolves key to its URL.
+func (i *InMemoryDB) GetURL(ctx context.Context, key string) (repository.URL, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.URL{}, err
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	url, ok := i.urls[key]
+	if !ok {
+		return repository.URL{}, repository.ErrURLNotFound
+	}
+	return url, nil
+}
+
+// DeleteURL marks the URL of key as deleted at the given time.
+func (i *InMemoryDB) DeleteURL(ctx context.Context, key string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	url, ok := i.urls[key]
+	if !ok {
+		return repository.ErrURLNotFound
+	}
+	if !url.Deleted() {
+		url.DeletedAt = at
+		i.urls[key] = url
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"URLShorteningService/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryDB(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+
+	if err := db.SaveURL(ctx, repository.URL{Key: "aaaa", LongURL: "https://example.com"}); err != nil {
+		t.Fatalf("Error saving url: %v.\n", err)
+	}
+	if err := db.SaveURL(ctx, repository.URL{Key: "aaaa", LongURL: "https://example.org"}); !errors.Is(err, repository.ErrKeyExists) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrKeyExists)
+	}
+
+	if u, err := db.GetURL(ctx, "aaaa"); err != nil || u.LongURL != "https://example.com" {
+		t.Errorf("Error incorrect url: Have %+v (%v), want %v.\n", u, err, "https://example.com")
+	}
+	if _, err := db.GetURL(ctx, "bbbb"); !errors.Is(err, repository.ErrURLNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrURLNotFound)
+	}
+
+	if err := db.DeleteURL(ctx, "aaaa", time.Unix(1700000000, 0)); err != nil {
+		t.Errorf("Error deleting url: %v.\n", err)
+	}
+	if u, _ := db.GetURL(ctx, "aaaa"); !u.Deleted() {
+		t.Errorf("Error url isn't marked as deleted: %+v.\n", u)
+	}
+	if err := db.DeleteURL(ctx, "bbbb", time.Now()); !errors.Is(err, repository.ErrURLNotFound) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, repository.ErrURLNotFound)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.GetURL(cancelled, "aaaa"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Error incorrect error: Have %v, want %v.\n", err, context.Canceled)
+	}
+}